@@ -0,0 +1,231 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/apis/polaris/v1alpha1"
+	bindingclient "github.com/aeraki-mesh/polaris2istio/pkg/client/clientset/versioned"
+	"github.com/aeraki-mesh/polaris2istio/pkg/config"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/yaml"
+)
+
+// listServicesTimeout is how long the services of the polaris namespace are listed
+const listServicesTimeout = 30 * time.Second
+
+// runBootstrap generates the source ServiceEntries of the services of a polaris namespace which are not bound yet
+func runBootstrap(args []string) error {
+	flags := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path of the YAML config file, its conversion settings, "+
+		"configRootNamespace and security.tokenFile are used")
+	polarisAPI := flags.String("polarisAPI", "127.0.0.1:8090", "HTTP address of the polaris server, the services "+
+		"are listed with its open API")
+	tokenFile := flags.String("tokenFile", "", "file of the polaris access token, default to security.tokenFile")
+	configRootNS := flags.String("configRootNS", "", "namespace of the ServiceEntries, default to "+
+		"configRootNamespace")
+	include := flags.String("include", "", "comma separated glob patterns of the services to include, "+
+		"all the services are included if it is empty")
+	exclude := flags.String("exclude", "", "comma separated glob patterns of the services to exclude")
+	external := flags.Bool("external", true, "generate MESH_EXTERNAL ServiceEntries, as aeraki.net/external")
+	workloadEntry := flags.Bool("workloadEntry", false,
+		"maintain the instances as standalone WorkloadEntries, as aeraki.net/workloadEntry")
+	apply := flags.Bool("apply", false, "create the ServiceEntries instead of printing them")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: polaris2istio bootstrap [flags] <polarisNamespace>\n\n"+
+			"Generates the source ServiceEntries of the services of the polaris namespace, the services which are "+
+			"already bound are skipped.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || flags.Arg(0) == "" {
+		flags.Usage()
+		return fmt.Errorf("expect one <polarisNamespace>")
+	}
+	namespace := flags.Arg(0)
+	quietLogs()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return err
+	}
+	if cfg.Mode != model.RegistryMethodServiceEntry {
+		return fmt.Errorf("only the ServiceEntry mode can be bootstrapped, the mode is %v", cfg.Mode)
+	}
+	if *configRootNS != "" {
+		cfg.ConfigRootNamespace = *configRootNS
+	}
+	if *tokenFile == "" {
+		*tokenFile = cfg.Security.TokenFile
+	}
+	if err := model.SetConversionOptions(cfg.ConversionOptions()); err != nil {
+		return fmt.Errorf("invalid conversion config: %v", err)
+	}
+	filter, err := model.NewServiceFilter(splitPatterns(*include), splitPatterns(*exclude))
+	if err != nil {
+		return err
+	}
+
+	var token polaris.Secret
+	if *tokenFile != "" {
+		data, err := ioutil.ReadFile(*tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the polaris access token: %v", err)
+		}
+		token = polaris.Secret(strings.TrimSpace(string(data)))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), listServicesTimeout)
+	defer cancel()
+	services, err := polaris.ListServices(ctx, http.DefaultClient, *polarisAPI, token, namespace)
+	if err != nil {
+		return err
+	}
+
+	restConfig, err := kubeconfig.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get kubeconfig: %v", err)
+	}
+	ic, err := istioclient.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	bc, err := bindingclient.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	bound, names, err := getBoundServices(ic, bc, cfg.ConfigRootNamespace)
+	if err != nil {
+		return err
+	}
+
+	var generated, skipped, filtered int
+	var errs []string
+	for _, service := range services {
+		if !filter.Match(service.Service) {
+			filtered++
+			continue
+		}
+		if by, exists := bound[namespace+"/"+service.Service]; exists {
+			fmt.Fprintf(os.Stderr, "skip %v/%v: bound by %v\n", namespace, service.Service, by)
+			skipped++
+			continue
+		}
+		se := model.NewSourceServiceEntry(&model.PolarisInfo{
+			PolarisNamespace: namespace,
+			PolarisService:   service.Service,
+			External:         strconv.FormatBool(*external),
+			WorkloadEntry:    strconv.FormatBool(*workloadEntry),
+		}, cfg.ConfigRootNamespace)
+		if names[se.Name] {
+			fmt.Fprintf(os.Stderr, "skip %v/%v: ServiceEntry %v/%v already exists\n", namespace, service.Service,
+				se.Namespace, se.Name)
+			skipped++
+			continue
+		}
+		if err := model.ValidateServiceEntry(se.Name, se.Namespace, &se.Spec); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		if !*apply {
+			data, err := yaml.Marshal(se)
+			if err != nil {
+				return fmt.Errorf("failed to marshal the ServiceEntry: %v", err)
+			}
+			if generated > 0 {
+				fmt.Println("---")
+			}
+			fmt.Print(string(data))
+			generated++
+			continue
+		}
+		_, err := ic.NetworkingV1alpha3().ServiceEntries(se.Namespace).Create(context.TODO(), se,
+			v1.CreateOptions{FieldManager: model.AerakiFieldManager})
+		if apierrors.IsAlreadyExists(err) {
+			fmt.Fprintf(os.Stderr, "skip %v/%v: ServiceEntry %v/%v already exists\n", namespace, service.Service,
+				se.Namespace, se.Name)
+			skipped++
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to create ServiceEntry %v/%v: %v", se.Namespace, se.Name, err))
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "created ServiceEntry %v/%v for %v/%v\n", se.Namespace, se.Name, namespace,
+			service.Service)
+		generated++
+	}
+
+	action := "generated"
+	if *apply {
+		action = "created"
+	}
+	fmt.Fprintf(os.Stderr, "%v services in polaris namespace %v: %v %v, %v skipped, %v filtered out\n",
+		len(services), namespace, generated, action, skipped, filtered)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// getBoundServices returns the polaris services bound in the namespace, and the names of the ServiceEntries in it
+func getBoundServices(ic *istioclient.Clientset, bc *bindingclient.Clientset, namespace string) (map[string]string,
+	map[string]bool, error) {
+	serviceEntries, err := ic.NetworkingV1alpha3().ServiceEntries(namespace).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the ServiceEntries of %v: %v", namespace, err)
+	}
+	var bindings []v1alpha1.PolarisServiceBinding
+	list, err := bc.PolarisV1alpha1().PolarisServiceBindings(namespace).List(context.TODO(), v1.ListOptions{})
+	if err == nil {
+		bindings = list.Items
+	} else if !apierrors.IsNotFound(err) {
+		// the CRD is optional, the services are only bound by the ServiceEntries if it is not installed
+		return nil, nil, fmt.Errorf("failed to list the PolarisServiceBindings of %v: %v", namespace, err)
+	}
+
+	names := make(map[string]bool, len(serviceEntries.Items))
+	for i := range serviceEntries.Items {
+		names[serviceEntries.Items[i].Name] = true
+	}
+	return model.BoundServices(serviceEntries.Items, bindings), names, nil
+}
+
+// splitPatterns splits the comma separated patterns, the empty ones are dropped
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
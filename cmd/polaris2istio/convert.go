@@ -0,0 +1,146 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/config"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/pkg/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// runConvert converts a dump of the polaris instances offline, and prints the configs which would be synced
+func runConvert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	file := flags.String("f", "-", "the polaris instances dump in JSON, the discover response of the polaris HTTP "+
+		"API or a saved InstancesResponse, - reads from the stdin")
+	configFile := flags.String("config", "", "path of the YAML config file, only its conversion settings are used")
+	configRootNS := flags.String("configRootNS", "polaris", "namespace of the generated configs")
+	external := flags.Bool("external", true, "convert to a MESH_EXTERNAL ServiceEntry, as aeraki.net/external")
+	workloadEntry := flags.Bool("workloadEntry", false,
+		"convert to standalone WorkloadEntries, as aeraki.net/workloadEntry")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: polaris2istio convert [flags]\n\n"+
+			"Converts a dump of the polaris instances to the configs polaris2istio would sync, without a cluster or "+
+			"a polaris server.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	quietLogs()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return err
+	}
+	if err := model.SetConversionOptions(cfg.ConversionOptions()); err != nil {
+		return fmt.Errorf("invalid conversion config: %v", err)
+	}
+
+	var data []byte
+	if *file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the instances dump: %v", err)
+	}
+	rsp, err := model.ParseInstancesDump(data)
+	if err != nil {
+		return err
+	}
+	polarisInfo := &model.PolarisInfo{
+		PolarisNamespace: rsp.GetNamespace(),
+		PolarisService:   rsp.GetService(),
+		External:         strconv.FormatBool(*external),
+	}
+	if *workloadEntry {
+		polarisInfo.WorkloadEntry = "true"
+	}
+	return printConverted(os.Stdout, rsp, polarisInfo, *configRootNS)
+}
+
+// printConverted prints the configs converted from the instances as YAML, the configs rejected by the istio
+// validation are printed as well and reported as the error
+func printConverted(out io.Writer, rsp *polarismodel.InstancesResponse, polarisInfo *model.PolarisInfo,
+	namespace string) error {
+	// the instances registered from the mesh are filtered out as they are by the sync
+	rsp = model.FilterInstances(rsp)
+	spec, annotations := model.ConvertServiceEntry(rsp, polarisInfo)
+	name := model.CovertServiceName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	serviceEntry := &v1alpha3.ServiceEntry{
+		TypeMeta: v1.TypeMeta{APIVersion: "networking.istio.io/v1alpha3", Kind: "ServiceEntry"},
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				model.ManagerLabel:  model.AerakiFieldManager,
+				model.RegistryLabel: model.PolarisRegistry,
+			},
+			Annotations: annotations,
+		},
+	}
+	spec.DeepCopyInto(&serviceEntry.Spec)
+	objects := []interface{}{serviceEntry}
+	validationErr := model.ValidateServiceEntry(name, namespace, spec)
+
+	if polarisInfo.IsWorkloadEntryMode() {
+		entries := model.ConvertWorkloadEntries(rsp, namespace)
+		for _, entry := range entries {
+			entry.TypeMeta = v1.TypeMeta{APIVersion: "networking.istio.io/v1alpha3", Kind: "WorkloadEntry"}
+			objects = append(objects, entry)
+		}
+		if validationErr == nil {
+			validationErr = model.ValidateWorkloadEntries(entries)
+		}
+	}
+
+	for i, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the converted configs: %v", err)
+		}
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return validationErr
+}
+
+// quietLogs sends the logs to the stderr and only keeps the warnings, so that the printed configs can be piped
+func quietLogs() {
+	opts := log.DefaultOptions()
+	opts.OutputPaths = []string{"stderr"}
+	opts.ErrorOutputPaths = []string{"stderr"}
+	opts.SetOutputLevel(log.DefaultScopeName, log.WarnLevel)
+	if err := log.Configure(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to configure the logs: %v\n", err)
+	}
+}
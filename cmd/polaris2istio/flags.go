@@ -0,0 +1,261 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/config"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/register"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+)
+
+// clusterFlags collects the repeated -polarisCluster flags
+type clusterFlags []config.Cluster
+
+func (c *clusterFlags) String() string {
+	names := make([]string, 0, len(*c))
+	for _, cluster := range *c {
+		names = append(names, cluster.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *clusterFlags) Set(value string) error {
+	cluster, err := polaris.ParseClusterConfig(value)
+	if err != nil {
+		return err
+	}
+	*c = append(*c, config.Cluster{
+		Name:      cluster.Name,
+		Addresses: cluster.Addresses,
+		Locality:  cluster.Locality,
+		Network:   cluster.Network,
+	})
+	return nil
+}
+
+// commandLine are the command line flags, the flags explicitly set override the config file
+type commandLine struct {
+	configFile               string
+	configReloadInterval     time.Duration
+	polarisAddress           string
+	clusters                 clusterFlags
+	registerNamespaceMapping string
+	registerGatewayHosts     string
+	// the other flags are bound to the fields of the config
+	config *config.Config
+}
+
+func parseFlags() *commandLine {
+	c := &commandLine{config: config.Default()}
+	cfg := c.config
+	flag.StringVar(&c.configFile, "config", "",
+		"path of the YAML config file, the environment variables and the flags set explicitly override it")
+	flag.DurationVar(&c.configReloadInterval, "configReloadInterval", 10*time.Second,
+		"interval to check whether the config file is changed, the changes of the conversion, the namespace mapping "+
+			"and the gateway hosts are applied without restarting, 0 disables the reload")
+	flag.StringVar(&c.polarisAddress, "polarisAddress", "127.0.0.1:8008",
+		"Polaris Address, it is used when no polarisCluster is specified")
+	flag.Var(&c.clusters, "polarisCluster",
+		"Polaris cluster in the format of name=<name>,address=<host:port>[,locality=<locality>][,network=<network>], "+
+			"it can be repeated for several clusters in descending priority")
+	flag.StringVar(&cfg.ConflictPolicy, "conflictPolicy", cfg.ConflictPolicy,
+		"how the instances of a service in several polaris clusters are merged, merge: all the instances, "+
+			"priority: only the instances of the first cluster which has any")
+	flag.UintVar(&cfg.Mode, "mode", cfg.Mode,
+		"Registry method, 1: matched ServiceEntry, 2: kubernetes Service and EndpointSlices, "+
+			"3: multi-cluster ServiceImport and EndpointSlices")
+	flag.StringVar(&cfg.ConfigRootNamespace, "configRootNS", cfg.ConfigRootNamespace,
+		"configRootNS for service registry")
+	flag.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun,
+		"compute the writes of the configs and log their diffs instead of making them, the diffs are also served "+
+			"on /debug/dryrun")
+	flag.StringVar(&cfg.TargetNamespace, "targetNS", cfg.TargetNamespace,
+		"namespace of the generated kubernetes services and service imports, default to configRootNS")
+	flag.BoolVar(&cfg.Register.Enabled, "registerServices", cfg.Register.Enabled,
+		"register the selected kubernetes services into polaris")
+	flag.StringVar(&cfg.Register.Selector, "registerSelector", cfg.Register.Selector,
+		"label selector of the kubernetes services registered into polaris")
+	flag.StringVar(&cfg.Register.Namespace, "registerNamespace", cfg.Register.Namespace,
+		"polaris namespace of the registered services, default to the kubernetes namespace")
+	flag.StringVar(&c.registerNamespaceMapping, "registerNamespaceMapping", "",
+		"mapping from kubernetes namespaces to polaris namespaces, in the format of <k8s-ns>=<polaris-ns>,...")
+	flag.StringVar(&cfg.Gateway.Service, "registerGateway", cfg.Gateway.Service,
+		"namespace/name of the mesh gateway service registered into polaris for the registerGatewayHosts")
+	flag.StringVar(&cfg.Gateway.Port, "registerGatewayPort", cfg.Gateway.Port,
+		"name of the mesh gateway service port registered into polaris")
+	flag.StringVar(&c.registerGatewayHosts, "registerGatewayHosts", "",
+		"mesh hosts published through the gateway, in the format of <mesh-host>=<polaris-ns>/<polaris-service>,...")
+	flag.BoolVar(&cfg.Security.TLS, "polarisTLS", cfg.Security.TLS, "connect to the polaris servers over TLS")
+	flag.StringVar(&cfg.Security.CAFile, "polarisCAFile", cfg.Security.CAFile,
+		"CA bundle to verify the polaris servers")
+	flag.StringVar(&cfg.Security.CertFile, "polarisCertFile", cfg.Security.CertFile,
+		"client certificate of the polaris servers")
+	flag.StringVar(&cfg.Security.KeyFile, "polarisKeyFile", cfg.Security.KeyFile,
+		"client key of the polaris servers")
+	flag.StringVar(&cfg.Security.TokenFile, "polarisTokenFile", cfg.Security.TokenFile,
+		"file of the access token of the polaris servers")
+	flag.StringVar(&cfg.Security.Secret, "polarisSecret", cfg.Security.Secret,
+		"namespace/name of the kubernetes secret with the ca.crt, tls.crt, tls.key and token of the polaris servers")
+	flag.StringVar(&cfg.Security.ServerName, "polarisServerName", cfg.Security.ServerName,
+		"server name to verify the polaris servers")
+	flag.DurationVar(&cfg.Security.ReloadInterval.Duration, "polarisCredentialReload",
+		cfg.Security.ReloadInterval.Duration, "interval to reload the rotated credentials of the polaris servers")
+	flag.BoolVar(&cfg.LeaderElection.Enabled, "leaderElect", cfg.LeaderElection.Enabled,
+		"elect a leader among the replicas with a kubernetes lease, only the leader writes the configs")
+	flag.StringVar(&cfg.LeaderElection.LeaseNamespace, "leaderElectionNamespace", cfg.LeaderElection.LeaseNamespace,
+		"namespace of the leader election lease, default to the namespace of the pod or configRootNS")
+	flag.StringVar(&cfg.LeaderElection.LeaseName, "leaderElectionName", cfg.LeaderElection.LeaseName,
+		"name of the leader election lease")
+	flag.BoolVar(&cfg.Sharding.Enabled, "sharding", cfg.Sharding.Enabled,
+		"shard the polaris services across the active replicas with the kubernetes leases")
+	flag.IntVar(&cfg.Sharding.Shards, "shards", cfg.Sharding.Shards,
+		"number of the shards of the polaris services, it should be the same in all the replicas")
+	flag.StringVar(&cfg.Monitoring.Address, "monitoringAddress", cfg.Monitoring.Address,
+		"listen address of the metrics on /metrics and the probes on /healthz and /readyz, such as :15014, "+
+			"empty (the default) disables them")
+	flag.DurationVar(&cfg.Monitoring.StaleThreshold.Duration, "staleThreshold", cfg.Monitoring.StaleThreshold.Duration,
+		"how long a service may stay out of sync before it is reported stale on /debug/freshness and the metrics")
+	flag.DurationVar(&cfg.Monitoring.StallTimeout.Duration, "stallTimeout", cfg.Monitoring.StallTimeout.Duration,
+		"how long an event is handled before the loop handling it is stalled and /healthz fails")
+	flag.BoolVar(&cfg.Monitoring.Debug, "debug", cfg.Monitoring.Debug,
+		"serve the sync state of the polaris services on /debug/syncz")
+	flag.BoolVar(&cfg.Events.Enabled, "events", cfg.Events.Enabled,
+		"record the sync outcomes as the kubernetes events of the source ServiceEntries, disabled by default")
+	flag.DurationVar(&cfg.Events.DedupInterval.Duration, "eventDedupInterval", cfg.Events.DedupInterval.Duration,
+		"how long an identical event of the same ServiceEntry is not recorded again")
+	flag.StringVar(&cfg.Webhook.Address, "webhookAddress", cfg.Webhook.Address,
+		"listen address of the admission webhooks of the polaris-managed ServiceEntries, empty disables them")
+	flag.StringVar(&cfg.Webhook.CertFile, "webhookCertFile", cfg.Webhook.CertFile,
+		"serving certificate of the admission webhooks")
+	flag.StringVar(&cfg.Webhook.KeyFile, "webhookKeyFile", cfg.Webhook.KeyFile,
+		"serving key of the admission webhooks")
+	flag.BoolVar(&cfg.Webhook.CheckPolarisService, "webhookCheckPolarisService", cfg.Webhook.CheckPolarisService,
+		"warn when the polaris service referred by the admitted ServiceEntry does not exist")
+	flag.Parse()
+	return c
+}
+
+// loadConfig loads the config file and overrides it with the flags set explicitly
+func (c *commandLine) loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(c.configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	flags := c.config
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "polarisAddress":
+			cfg.Clusters = []config.Cluster{{Name: "default", Addresses: []string{c.polarisAddress}}}
+		case "polarisCluster":
+			cfg.Clusters = c.clusters
+		case "conflictPolicy":
+			cfg.ConflictPolicy = flags.ConflictPolicy
+		case "mode":
+			cfg.Mode = flags.Mode
+		case "configRootNS":
+			cfg.ConfigRootNamespace = flags.ConfigRootNamespace
+		case "targetNS":
+			cfg.TargetNamespace = flags.TargetNamespace
+		case "dry-run":
+			cfg.DryRun = flags.DryRun
+		case "registerServices":
+			cfg.Register.Enabled = flags.Register.Enabled
+		case "registerSelector":
+			cfg.Register.Selector = flags.Register.Selector
+		case "registerNamespace":
+			cfg.Register.Namespace = flags.Register.Namespace
+		case "registerNamespaceMapping":
+			mapping, err := register.ParseNamespaceMapping(c.registerNamespaceMapping)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			cfg.Register.NamespaceMapping = mapping
+		case "registerGateway":
+			cfg.Gateway.Service = flags.Gateway.Service
+		case "registerGatewayPort":
+			cfg.Gateway.Port = flags.Gateway.Port
+		case "registerGatewayHosts":
+			hosts, err := register.ParseGatewayHosts(c.registerGatewayHosts)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			cfg.Gateway.Hosts = make(map[string]string, len(hosts))
+			for host, key := range hosts {
+				cfg.Gateway.Hosts[host] = key.Namespace + "/" + key.Service
+			}
+		case "polarisTLS":
+			cfg.Security.TLS = flags.Security.TLS
+		case "polarisCAFile":
+			cfg.Security.CAFile = flags.Security.CAFile
+		case "polarisCertFile":
+			cfg.Security.CertFile = flags.Security.CertFile
+		case "polarisKeyFile":
+			cfg.Security.KeyFile = flags.Security.KeyFile
+		case "polarisTokenFile":
+			cfg.Security.TokenFile = flags.Security.TokenFile
+		case "polarisSecret":
+			cfg.Security.Secret = flags.Security.Secret
+		case "polarisServerName":
+			cfg.Security.ServerName = flags.Security.ServerName
+		case "polarisCredentialReload":
+			cfg.Security.ReloadInterval = flags.Security.ReloadInterval
+		case "leaderElect":
+			cfg.LeaderElection.Enabled = flags.LeaderElection.Enabled
+		case "leaderElectionNamespace":
+			cfg.LeaderElection.LeaseNamespace = flags.LeaderElection.LeaseNamespace
+		case "leaderElectionName":
+			cfg.LeaderElection.LeaseName = flags.LeaderElection.LeaseName
+		case "monitoringAddress":
+			cfg.Monitoring.Address = flags.Monitoring.Address
+		case "staleThreshold":
+			cfg.Monitoring.StaleThreshold = flags.Monitoring.StaleThreshold
+		case "stallTimeout":
+			cfg.Monitoring.StallTimeout = flags.Monitoring.StallTimeout
+		case "debug":
+			cfg.Monitoring.Debug = flags.Monitoring.Debug
+		case "events":
+			cfg.Events.Enabled = flags.Events.Enabled
+		case "eventDedupInterval":
+			cfg.Events.DedupInterval = flags.Events.DedupInterval
+		case "webhookAddress":
+			cfg.Webhook.Address = flags.Webhook.Address
+		case "webhookCertFile":
+			cfg.Webhook.CertFile = flags.Webhook.CertFile
+		case "webhookKeyFile":
+			cfg.Webhook.KeyFile = flags.Webhook.KeyFile
+		case "webhookCheckPolarisService":
+			cfg.Webhook.CheckPolarisService = flags.Webhook.CheckPolarisService
+		case "sharding":
+			cfg.Sharding.Enabled = flags.Sharding.Enabled
+		case "shards":
+			cfg.Sharding.Shards = flags.Sharding.Shards
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	cfg.SetDefaultCluster(c.polarisAddress)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
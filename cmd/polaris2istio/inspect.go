@@ -0,0 +1,229 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/config"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// runInspect compares the instances of a polaris service with the endpoints of its ServiceEntry
+func runInspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path of the YAML config file, its polaris clusters, conversion settings "+
+		"and configRootNamespace are used")
+	polarisAddress := flags.String("polarisAddress", "127.0.0.1:8008",
+		"Polaris Address, it is used when no polaris cluster is configured")
+	configRootNS := flags.String("configRootNS", "", "namespace of the ServiceEntry, default to configRootNamespace")
+	output := flags.String("o", "table", "output format, table or json")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: polaris2istio inspect [flags] <polarisNamespace>/<service>\n\n"+
+			"Compares the instances of the polaris service with the endpoints of its ServiceEntry.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	parts := strings.SplitN(flags.Arg(0), "/", 2)
+	if flags.NArg() != 1 || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		flags.Usage()
+		return fmt.Errorf("expect one <polarisNamespace>/<service>")
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("unknown output format: %v", *output)
+	}
+	namespace, service := parts[0], parts[1]
+	quietLogs()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return err
+	}
+	cfg.SetDefaultCluster(*polarisAddress)
+	if cfg.Mode != model.RegistryMethodServiceEntry {
+		return fmt.Errorf("only the ServiceEntry mode can be inspected, the mode is %v", cfg.Mode)
+	}
+	if *configRootNS != "" {
+		cfg.ConfigRootNamespace = *configRootNS
+	}
+	if err := model.SetConversionOptions(cfg.ConversionOptions()); err != nil {
+		return fmt.Errorf("invalid conversion config: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	clusters, err := connectClusters(cfg, stop)
+	if err != nil {
+		return fmt.Errorf("failed to connect to polaris clusters: %v", err)
+	}
+	client, err := polaris.NewFederatedClient(clusters, polaris.ConflictPolicy(cfg.ConflictPolicy), cfg.SDKOptions())
+	if err != nil {
+		return err
+	}
+	rsp, err := client.GetPolarisAllInstances(namespace, service)
+	if err != nil {
+		return fmt.Errorf("failed to get the instances of polaris service %v/%v: %v", namespace, service, err)
+	}
+
+	ic, err := newIstioClient()
+	if err != nil {
+		return err
+	}
+	se, entries, err := getServiceEntry(ic, cfg.ConfigRootNamespace, namespace, service)
+	if err != nil {
+		return err
+	}
+	report := model.Inspect(rsp, se, entries)
+
+	if *output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return printInspectReport(os.Stdout, report)
+}
+
+func newIstioClient() (*istioclient.Clientset, error) {
+	restConfig, err := kubeconfig.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig: %v", err)
+	}
+	return istioclient.NewForConfig(restConfig)
+}
+
+// getServiceEntry returns the ServiceEntry of the polaris service and the WorkloadEntries it selects, the
+// ServiceEntry is nil if it is not found
+func getServiceEntry(ic *istioclient.Clientset, configRootNS, namespace, service string) (*v1alpha3.ServiceEntry,
+	[]*v1alpha3.WorkloadEntry, error) {
+	name := model.CovertServiceName(namespace, service)
+	se, err := ic.NetworkingV1alpha3().ServiceEntries(configRootNS).Get(context.TODO(), name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get ServiceEntry %v/%v: %v", configRootNS, name, err)
+	}
+	if se.Spec.GetWorkloadSelector() == nil {
+		return se, nil, nil
+	}
+	list, err := ic.NetworkingV1alpha3().WorkloadEntries(configRootNS).List(context.TODO(), v1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", model.PolarisServiceLabel,
+			model.WorkloadSelectorLabelValue(namespace, service)),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the WorkloadEntries of %v/%v: %v", configRootNS, name, err)
+	}
+	entries := make([]*v1alpha3.WorkloadEntry, 0, len(list.Items))
+	for i := range list.Items {
+		entries = append(entries, &list.Items[i])
+	}
+	return se, entries, nil
+}
+
+// printInspectReport prints the polaris side and the istio side of the endpoints side by side
+func printInspectReport(out io.Writer, report *model.InspectReport) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Polaris service:\t%v/%v\n", report.PolarisNamespace, report.PolarisService)
+	if report.ServiceEntry == "" {
+		fmt.Fprintf(w, "ServiceEntry:\tnot found\n")
+	} else {
+		fmt.Fprintf(w, "ServiceEntry:\t%v\n", report.ServiceEntry)
+	}
+	fmt.Fprintf(w, "Revision:\tpolaris %v, istio %v%v\n", report.PolarisRevision, orNone(report.IstioRevision),
+		driftMark(report.RevisionDrift))
+	fmt.Fprintf(w, "Ports:\tpolaris [%v], istio [%v]%v\n", strings.Join(report.PolarisPorts, " "),
+		strings.Join(report.IstioPorts, " "), driftMark(report.PortDrift))
+	if status := report.SyncStatus; status != nil {
+		fmt.Fprintf(w, "Last sync:\t%v", status.LastSyncTime)
+		if status.LastError != "" {
+			fmt.Fprintf(w, ", error: %v", status.LastError)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "ENDPOINT\tPOLARIS\tISTIO\tSTATUS")
+	for _, endpoint := range report.Endpoints {
+		status := "ok"
+		if len(endpoint.Problems) > 0 {
+			status = strings.Join(endpoint.Problems, ", ")
+		} else if endpoint.Filtered != "" {
+			status = "filtered out"
+		}
+		if endpoint.Filtered != "" {
+			status += " (" + endpoint.Filtered + ")"
+		}
+		fmt.Fprintf(w, "%v:%v\t%v\t%v\t%v\n", endpoint.Address, endpoint.Port, describeEndpoint(endpoint.Polaris),
+			describeEndpoint(endpoint.Istio), status)
+	}
+	fmt.Fprintf(w, "\n%v problems found\n", report.Problems())
+	return w.Flush()
+}
+
+// describeEndpoint describes the side of the endpoint in a column, - if it is absent
+func describeEndpoint(side *model.EndpointSide) string {
+	if side == nil {
+		return "-"
+	}
+	names := make([]string, 0, len(side.Ports))
+	for name := range side.Ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := []string{fmt.Sprintf("weight=%d", side.Weight)}
+	if len(names) > 0 {
+		fields = append([]string{strings.Join(names, ",")}, fields...)
+	}
+	if side.Healthy != nil && !*side.Healthy {
+		fields = append(fields, "unhealthy")
+	}
+	if side.Isolated {
+		fields = append(fields, "isolated")
+	}
+	if side.Cluster != "" {
+		fields = append(fields, "cluster="+side.Cluster)
+	}
+	return strings.Join(fields, " ")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+func driftMark(drift bool) string {
+	if drift {
+		return "  DRIFT"
+	}
+	return ""
+}
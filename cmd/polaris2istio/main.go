@@ -16,37 +16,287 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"reflect"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/aeraki-mesh/polaris2istio/pkg/config"
+	"github.com/aeraki-mesh/polaris2istio/pkg/health"
+	"github.com/aeraki-mesh/polaris2istio/pkg/leader"
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	"github.com/aeraki-mesh/polaris2istio/pkg/monitoring"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/register"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
 	watcher "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/watcher"
+	"github.com/aeraki-mesh/polaris2istio/pkg/webhook"
 	"istio.io/pkg/log"
+	"k8s.io/client-go/kubernetes"
+	kubeconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
-const (
-	defaultPolarisAddress = "127.0.0.1:8008"
-	defaultMethod         = uint(1) // matched ServiceEntry
-	defaultConfigRootNS   = "polaris"
-)
+type runnable interface {
+	Run(stop <-chan struct{})
+}
+
+// pingTimeout is how long the polaris clusters are dialed when the readiness is probed
+const pingTimeout = 2 * time.Second
+
+// subcommands are the offline tools, the controller is run without a subcommand
+var subcommands = map[string]func(args []string) error{
+	"convert":   runConvert,
+	"inspect":   runInspect,
+	"bootstrap": runBootstrap,
+}
 
 func main() {
-	polarisAddress := flag.String("polarisAddress", defaultPolarisAddress, "Polaris Address")
-	defaultMethod := flag.Uint("mode", defaultMethod, "Registry method")
-	configRootNS := flag.String("configRootNS", defaultConfigRootNS, "configRootNS for service registry")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		if run, exists := subcommands[os.Args[1]]; exists {
+			if err := run(os.Args[2:]); err != nil && err != flag.ErrHelp {
+				fmt.Fprintf(os.Stderr, "polaris2istio %v: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 
-	controller, err := watcher.NewServiceWatcher(*polarisAddress, *defaultMethod, *configRootNS)
+	cmd := parseFlags()
+	cfg, err := cmd.loadConfig()
+	if err != nil {
+		log.Errorf("Invalid config: %v", err)
+		os.Exit(1)
+	}
+	if err := model.SetConversionOptions(cfg.ConversionOptions()); err != nil {
+		log.Errorf("Invalid conversion config: %v", err)
+		os.Exit(1)
+	}
+	if cfg.DryRun {
+		log.Infof("Running in the dry-run mode, the configs are not written")
+	}
 
-	stopChan := make(chan struct{}, 1)
-	go controller.Run(stopChan)
+	proxyStop := make(chan struct{})
+	defer close(proxyStop)
+	clusters, err := connectClusters(cfg, proxyStop)
+	if err != nil {
+		log.Errorf("Fialed to connect to polaris clusters: %v", err)
+		return
+	}
+
+	elector, err := leader.NewElector(cfg.LeaderElectionOptions())
+	if err != nil {
+		log.Errorf("Fialed to create leader elector: %v", err)
+		return
+	}
+
+	sharder, err := leader.NewSharder(cfg.ShardOptions())
+	if err != nil {
+		log.Errorf("Fialed to create sharder: %v", err)
+		return
+	}
+
+	watcherOpts := cfg.WatcherOptions()
+	watcherOpts.Clusters = clusters
+	// the sharded services are written by their owners, otherwise they are written by the leader
+	if cfg.Sharding.Enabled {
+		watcherOpts.Owns = sharder.Owns
+	} else {
+		watcherOpts.IsLeader = elector.IsLeader
+	}
+	controller, err := watcher.NewServiceWatcher(watcherOpts)
 	if err != nil {
 		log.Errorf("Fialed to run controller: %v", err)
 		return
 	}
 
+	// the registrars deregister their instances from polaris when they are stopped
+	var registrars []runnable
+	// the instances are registered into the first polaris cluster
+	var provider *polaris.PolarisProviderClient
+	// the registrars share the kubernetes client
+	var kc kubernetes.Interface
+	if cfg.Register.Enabled || cfg.Gateway.Service != "" {
+		provider, err = polaris.NewPolarisProviderClient(clusters[0].Addresses, register.HeartbeatTTL,
+			cfg.SDKOptions())
+		if err != nil {
+			log.Errorf("Fialed to create polaris provider client: %v", err)
+			return
+		}
+		defer provider.Destroy()
+		kc, err = newKubeClient()
+		if err != nil {
+			log.Errorf("Fialed to create kubernetes client: %v", err)
+			return
+		}
+	}
+
+	var registerController *register.Controller
+	if cfg.Register.Enabled {
+		registerController, err = register.NewController(kc, provider, cfg.RegisterOptions())
+		if err != nil {
+			log.Errorf("Fialed to run register controller: %v", err)
+			return
+		}
+		registrars = append(registrars, registerController)
+	}
+
+	var gatewayRegistrar *register.GatewayRegistrar
+	if cfg.Gateway.Service != "" {
+		gatewayRegistrar, err = register.NewGatewayRegistrar(kc, provider, cfg.GatewayOptions())
+		if err != nil {
+			log.Errorf("Fialed to run gateway registrar: %v", err)
+			return
+		}
+		registrars = append(registrars, gatewayRegistrar)
+	}
+
+	stopChan := make(chan struct{})
+	wg := sync.WaitGroup{}
+	if cfg.Monitoring.Address != "" {
+		server, err := monitoring.NewServer(cfg.Monitoring.Address)
+		if err != nil {
+			log.Errorf("Fialed to run monitoring server: %v", err)
+			return
+		}
+		metrics.Registry.MustRegister(controller.Freshness())
+		server.Handle("/debug/freshness", controller.Freshness())
+		if syncState := controller.SyncState(); syncState != nil {
+			server.Handle("/debug/syncz", syncState)
+		}
+		if dryRun := controller.DryRun(); dryRun != nil {
+			server.Handle("/debug/dryrun", dryRun)
+		}
+		health.Default.Require(health.ConditionServiceEntries, health.ConditionInitialSync)
+		health.Default.AddReadinessCheck("polaris", func() error {
+			return polaris.PingClusters(clusters, pingTimeout)
+		})
+		health.Default.SetStallTimeout(cfg.Monitoring.StallTimeout.Duration)
+		server.Handle("/healthz", health.Default.HealthzHandler())
+		server.Handle("/readyz", health.Default.ReadyzHandler())
+		go server.Run(stopChan)
+	}
+	// the webhooks are served by all the replicas, they only read from the polaris
+	if cfg.Webhook.Address != "" {
+		webhookOpts := cfg.WebhookOptions()
+		if cfg.Webhook.CheckPolarisService {
+			webhookOpts.LookupService = controller.LookupService
+		}
+		server, err := webhook.NewServer(webhookOpts)
+		if err != nil {
+			log.Errorf("Fialed to run webhook server: %v", err)
+			return
+		}
+		go server.Run(stopChan)
+	}
+	go controller.Run(stopChan)
+
+	if cmd.configFile != "" && cmd.configReloadInterval > 0 {
+		reloader, err := config.NewReloader(cmd.configFile, cmd.configReloadInterval, cfg, cmd.loadConfig)
+		if err != nil {
+			log.Errorf("Fialed to watch config file: %v", err)
+			return
+		}
+		reloader.OnChange(func(old *config.Config, new *config.Config) {
+			if !reflect.DeepEqual(old.Conversion, new.Conversion) {
+				if err := model.SetConversionOptions(new.ConversionOptions()); err != nil {
+					log.Errorf("Invalid conversion config: %v", err)
+				} else {
+					controller.Resync()
+				}
+			}
+			if registerController != nil && (old.Register.Namespace != new.Register.Namespace ||
+				!reflect.DeepEqual(old.Register.NamespaceMapping, new.Register.NamespaceMapping)) {
+				registerController.SetNamespaceMapping(new.Register.Namespace, new.Register.NamespaceMapping)
+			}
+			if gatewayRegistrar != nil && !reflect.DeepEqual(old.Gateway.Hosts, new.Gateway.Hosts) {
+				gatewayRegistrar.SetHosts(new.GatewayOptions().Hosts)
+			}
+		})
+		go reloader.Run(stopChan)
+	}
+	// the registrars only run in the leader, they are started after the leader catches up with the changes
+	// made while it was a standby
+	var startMutex sync.Mutex
+	elector.OnStartedLeading(func() {
+		if cfg.LeaderElection.Enabled && !cfg.Sharding.Enabled {
+			controller.Resync()
+		}
+		startMutex.Lock()
+		defer startMutex.Unlock()
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+		for _, registrar := range registrars {
+			wg.Add(1)
+			go func(r runnable) {
+				defer wg.Done()
+				r.Run(stopChan)
+			}(registrar)
+		}
+	})
+	electorStop := make(chan struct{})
+	electorDone := make(chan struct{})
+	go func() {
+		elector.Run(electorStop)
+		close(electorDone)
+	}()
+	// the newly acquired shards are converted from the warm caches at once
+	sharder.OnAcquired(controller.Resync)
+	sharderDone := make(chan struct{})
+	go func() {
+		sharder.Run(electorStop)
+		close(sharderDone)
+	}()
+
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-	<-signalChan
-	stopChan <- struct{}{}
+	select {
+	case <-signalChan:
+	case <-electorDone:
+		log.Errorf("Lost the leadership, exiting")
+	}
+	startMutex.Lock()
+	close(stopChan)
+	startMutex.Unlock()
+	// wait for the registered instances to be deregistered before the lease is released,
+	// otherwise the new leader's instances may be deregistered
+	wg.Wait()
+	close(electorStop)
+	<-electorDone
+	<-sharderDone
+}
+
+// connectClusters returns the configs of the polaris clusters, the polaris sdk only connects over the plaintext
+// grpc, so the secured clusters are connected through the local proxies until the stop channel is closed
+func connectClusters(cfg *config.Config, stop <-chan struct{}) ([]polaris.ClusterConfig, error) {
+	clusters := cfg.ClusterConfigs()
+	security := cfg.SecurityOptions()
+	if !security.Enabled() {
+		return clusters, nil
+	}
+	for i := range clusters {
+		for j, address := range clusters[i].Addresses {
+			proxy, err := polaris.NewSecureProxy(address, security)
+			if err != nil {
+				return nil, fmt.Errorf("cluster %v: %v", clusters[i].Name, err)
+			}
+			go proxy.Run(stop)
+			clusters[i].Addresses[j] = proxy.Address()
+		}
+	}
+	return clusters, nil
+}
+
+// newKubeClient creates the kubernetes client shared by the registrars
+func newKubeClient() (kubernetes.Interface, error) {
+	restConfig, err := kubeconfig.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig: %v", err)
+	}
+	return kubernetes.NewForConfig(restConfig)
 }
@@ -0,0 +1,41 @@
+#!/usr/bin/env bash
+
+# Copyright Aeraki Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Regenerates the deepcopy functions and the clientset of the polaris.aeraki.net API.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+MODULE=github.com/aeraki-mesh/polaris2istio
+SCRIPT_ROOT=$(dirname "${BASH_SOURCE[0]}")/..
+CODEGEN_VERSION=${CODEGEN_VERSION:-v0.24.1}
+CODEGEN_PKG=${CODEGEN_PKG:-$(go env GOMODCACHE)/k8s.io/code-generator@${CODEGEN_VERSION}}
+
+if [[ ! -d "${CODEGEN_PKG}" ]]; then
+  go mod download "k8s.io/code-generator@${CODEGEN_VERSION}"
+fi
+
+OUTPUT=$(mktemp -d)
+trap 'rm -rf "${OUTPUT}"' EXIT
+
+bash "${CODEGEN_PKG}/generate-groups.sh" "deepcopy,client" \
+  "${MODULE}/pkg/client" "${MODULE}/pkg/apis" \
+  "polaris:v1alpha1" \
+  --output-base "${OUTPUT}" \
+  --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt"
+
+cp -r "${OUTPUT}/${MODULE}/pkg/." "${SCRIPT_ROOT}/pkg/"
@@ -0,0 +1,86 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PolarisServiceBinding declares a polaris service to be synced into the mesh and how it is projected
+type PolarisServiceBinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PolarisServiceBindingSpec   `json:"spec"`
+	Status PolarisServiceBindingStatus `json:"status,omitempty"`
+}
+
+// PolarisServiceBindingSpec is the polaris service to bind and how it is projected
+type PolarisServiceBindingSpec struct {
+	// PolarisNamespace is the namespace of the polaris service
+	PolarisNamespace string `json:"polarisNamespace"`
+	// PolarisService is the name of the polaris service
+	PolarisService string `json:"polarisService"`
+	// External is whether the generated ServiceEntry is MESH_EXTERNAL, it is true if it is not set
+	// +optional
+	External *bool `json:"external,omitempty"`
+	// WorkloadEntry maintains the instances as standalone WorkloadEntries selected by the ServiceEntry
+	// +optional
+	WorkloadEntry bool `json:"workloadEntry,omitempty"`
+}
+
+// PolarisServiceBindingStatus is the status of the sync of the bound polaris service
+type PolarisServiceBindingStatus struct {
+	// ObservedGeneration is the generation of the binding the status is written for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastSyncTime is when the polaris service was last synced, successfully or not
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastSuccessTime is when the polaris service was last synced successfully
+	// +optional
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+	// Revision is the polaris revision last synced successfully
+	// +optional
+	Revision string `json:"revision,omitempty"`
+	// Endpoints are the numbers of the instances when the polaris service was last synced
+	// +optional
+	Endpoints EndpointCounts `json:"endpoints,omitempty"`
+	// LastError is the error of the last sync, it is empty if the last sync succeeded
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// EndpointCounts are the numbers of the instances of the polaris service
+type EndpointCounts struct {
+	Total int32 `json:"total"`
+	// Healthy is the number of the healthy instances which are not filtered out
+	Healthy int32 `json:"healthy"`
+	// Filtered is the number of the instances registered from the mesh or rejected by the conversion options
+	Filtered int32 `json:"filtered"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PolarisServiceBindingList is a list of PolarisServiceBindings
+type PolarisServiceBindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []PolarisServiceBinding `json:"items"`
+}
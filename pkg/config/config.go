@@ -0,0 +1,564 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/events"
+	"github.com/aeraki-mesh/polaris2istio/pkg/freshness"
+	"github.com/aeraki-mesh/polaris2istio/pkg/health"
+	"github.com/aeraki-mesh/polaris2istio/pkg/leader"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/register"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	watcher "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/watcher"
+	"github.com/aeraki-mesh/polaris2istio/pkg/webhook"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultPolarisAddress = "127.0.0.1:8008"
+	defaultClusterName    = "default"
+	defaultConfigRootNS   = "polaris"
+	defaultRegisterLabel  = "aeraki.net/polarisRegister=true"
+	defaultGatewayPort    = "http2"
+	defaultReload         = 30 * time.Second
+	defaultProtocol       = "grpc"
+	defaultConnectTimeout = 5 * time.Second
+	// podNamespaceEnv is the namespace of the pod set by the downward API
+	podNamespaceEnv = "POD_NAMESPACE"
+)
+
+// Config is the configuration of polaris2istio and the embedded polaris-go sdk
+type Config struct {
+	// Mode is the registry method, 1: matched ServiceEntry, 2: kubernetes Service and EndpointSlices,
+	// 3: multi-cluster ServiceImport and EndpointSlices
+	Mode uint `json:"mode,omitempty"`
+	// ConfigRootNamespace is the namespace of the matched ServiceEntries
+	ConfigRootNamespace string `json:"configRootNamespace,omitempty"`
+	// TargetNamespace is the namespace of the generated kubernetes services and service imports,
+	// default to the ConfigRootNamespace
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// ConflictPolicy decides how the instances of a polaris service in several clusters are merged
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+	// Clusters are the polaris clusters in descending priority
+	Clusters []Cluster `json:"clusters,omitempty"`
+	// DryRun computes the writes of the configs and logs their diffs instead of making them
+	DryRun bool `json:"dryRun,omitempty"`
+
+	Security       Security       `json:"security,omitempty"`
+	Conversion     Conversion     `json:"conversion,omitempty"`
+	Register       Register       `json:"register,omitempty"`
+	Gateway        Gateway        `json:"gateway,omitempty"`
+	LeaderElection LeaderElection `json:"leaderElection,omitempty"`
+	Sharding       Sharding       `json:"sharding,omitempty"`
+	Monitoring     Monitoring     `json:"monitoring,omitempty"`
+	Events         Events         `json:"events,omitempty"`
+	Webhook        Webhook        `json:"webhook,omitempty"`
+	SDK            SDK            `json:"sdk,omitempty"`
+}
+
+// Cluster is a polaris cluster
+type Cluster struct {
+	Name      string   `json:"name,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	Locality  string   `json:"locality,omitempty"`
+	Network   string   `json:"network,omitempty"`
+}
+
+// Security are the credentials of the polaris servers
+type Security struct {
+	TLS            bool        `json:"tls,omitempty"`
+	CAFile         string      `json:"caFile,omitempty"`
+	CertFile       string      `json:"certFile,omitempty"`
+	KeyFile        string      `json:"keyFile,omitempty"`
+	TokenFile      string      `json:"tokenFile,omitempty"`
+	Secret         string      `json:"secret,omitempty"`
+	ServerName     string      `json:"serverName,omitempty"`
+	ReloadInterval v1.Duration `json:"reloadInterval,omitempty"`
+}
+
+// Conversion controls how the polaris instances are converted to the istio configs
+type Conversion struct {
+	HostnameTemplate string            `json:"hostnameTemplate,omitempty"`
+	HealthPolicy     string            `json:"healthPolicy,omitempty"`
+	InstanceFilter   map[string]string `json:"instanceFilter,omitempty"`
+}
+
+// Register controls the registration of the kubernetes services into the polaris
+type Register struct {
+	Enabled          bool              `json:"enabled,omitempty"`
+	Selector         string            `json:"selector,omitempty"`
+	Namespace        string            `json:"namespace,omitempty"`
+	NamespaceMapping map[string]string `json:"namespaceMapping,omitempty"`
+}
+
+// Gateway controls the registration of the mesh gateway into the polaris
+type Gateway struct {
+	// Service is the namespace/name of the gateway service, the gateway is not registered if it is empty
+	Service string `json:"service,omitempty"`
+	Port    string `json:"port,omitempty"`
+	// Hosts maps the mesh hosts to the <polaris-namespace>/<polaris-service>
+	Hosts map[string]string `json:"hosts,omitempty"`
+}
+
+// LeaderElection elects the leader among the replicas, only the leader writes to the kubernetes and the polaris
+type LeaderElection struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// LeaseNamespace is the namespace of the lease, default to the namespace of the pod or the ConfigRootNamespace
+	LeaseNamespace string      `json:"leaseNamespace,omitempty"`
+	LeaseName      string      `json:"leaseName,omitempty"`
+	LeaseDuration  v1.Duration `json:"leaseDuration,omitempty"`
+	RenewDeadline  v1.Duration `json:"renewDeadline,omitempty"`
+	RetryPeriod    v1.Duration `json:"retryPeriod,omitempty"`
+}
+
+// Sharding shards the polaris services across the active replicas, only the owner of a service writes its configs.
+// The leases are in the namespace of the leader election, and they are renewed as the leader election lease.
+type Sharding struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// LeaseName is the name prefix of the sharding leases
+	LeaseName string `json:"leaseName,omitempty"`
+	// Shards is the number of the shards, it should be the same in all the replicas and much more than the replicas
+	Shards int `json:"shards,omitempty"`
+}
+
+// Monitoring serves the metrics over http
+type Monitoring struct {
+	// Address is the listen address of the metrics and the probes, they are not served if it is empty, which is the
+	// default
+	Address string `json:"address,omitempty"`
+	// StaleThreshold is how long a service may stay out of sync before it is reported stale
+	StaleThreshold v1.Duration `json:"staleThreshold,omitempty"`
+	// StallTimeout is how long an event is handled before it is not live on /healthz
+	StallTimeout v1.Duration `json:"stallTimeout,omitempty"`
+	// Debug serves the sync state of the polaris services on /debug/syncz
+	Debug bool `json:"debug,omitempty"`
+}
+
+// Events records the sync outcomes as the kubernetes events of the source ServiceEntries
+type Events struct {
+	// Enabled records the events, it requires the permission to create the events in the namespaces of the sources
+	Enabled bool `json:"enabled,omitempty"`
+	// DedupInterval is how long an identical event of the same ServiceEntry is not recorded again
+	DedupInterval v1.Duration `json:"dedupInterval,omitempty"`
+}
+
+// Webhook serves the admission webhooks of the polaris-managed ServiceEntries over https
+type Webhook struct {
+	// Address is the listen address of the webhooks, the webhooks are not served if it is empty
+	Address  string `json:"address,omitempty"`
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// CheckPolarisService warns when the polaris service referred by the ServiceEntry does not exist
+	CheckPolarisService bool `json:"checkPolarisService,omitempty"`
+}
+
+// SDK are the settings passed through to the polaris-go sdk
+type SDK struct {
+	Protocol               string      `json:"protocol,omitempty"`
+	ConnectTimeout         v1.Duration `json:"connectTimeout,omitempty"`
+	MessageTimeout         v1.Duration `json:"messageTimeout,omitempty"`
+	RequestTimeout         v1.Duration `json:"requestTimeout,omitempty"`
+	MaxRetryTimes          int         `json:"maxRetryTimes,omitempty"`
+	RetryInterval          v1.Duration `json:"retryInterval,omitempty"`
+	CacheDir               string      `json:"cacheDir,omitempty"`
+	ServiceExpireTime      v1.Duration `json:"serviceExpireTime,omitempty"`
+	ServiceRefreshInterval v1.Duration `json:"serviceRefreshInterval,omitempty"`
+}
+
+// Default returns the default config
+func Default() *Config {
+	return &Config{
+		Mode:                model.RegistryMethodServiceEntry,
+		ConfigRootNamespace: defaultConfigRootNS,
+		ConflictPolicy:      string(polaris.ConflictPolicyMerge),
+		Security:            Security{ReloadInterval: v1.Duration{Duration: defaultReload}},
+		Conversion: Conversion{
+			HostnameTemplate: model.DefaultHostnameTemplate,
+			HealthPolicy:     model.HealthPolicyAll,
+		},
+		Register: Register{Selector: defaultRegisterLabel},
+		Gateway:  Gateway{Port: defaultGatewayPort},
+		LeaderElection: LeaderElection{
+			LeaseName:     leader.DefaultLeaseName,
+			LeaseDuration: v1.Duration{Duration: leader.DefaultLeaseDuration},
+			RenewDeadline: v1.Duration{Duration: leader.DefaultRenewDeadline},
+			RetryPeriod:   v1.Duration{Duration: leader.DefaultRetryPeriod},
+		},
+		Monitoring: Monitoring{
+			StaleThreshold: v1.Duration{Duration: freshness.DefaultThreshold},
+			StallTimeout:   v1.Duration{Duration: health.DefaultStallTimeout},
+		},
+		Sharding: Sharding{
+			LeaseName: leader.DefaultShardLeaseName,
+			Shards:    leader.DefaultShards,
+		},
+		Events: Events{
+			DedupInterval: v1.Duration{Duration: events.DefaultDedupInterval},
+		},
+		SDK: SDK{
+			Protocol:       defaultProtocol,
+			ConnectTimeout: v1.Duration{Duration: defaultConnectTimeout},
+		},
+	}
+}
+
+// Load reads the config file over the defaults, and applies the overrides of the environment variables.
+// The unknown fields in the file are rejected. The default config is returned if the path is empty.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %v: %v", path, err)
+		}
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid config file %v: %v", path, err)
+		}
+	}
+	if err := applyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// SetDefaultCluster uses the polaris address as the only cluster if no cluster is configured
+func (c *Config) SetDefaultCluster(address string) {
+	if len(c.Clusters) > 0 {
+		return
+	}
+	if address == "" {
+		address = defaultPolarisAddress
+	}
+	c.Clusters = []Cluster{{Name: defaultClusterName, Addresses: []string{address}}}
+}
+
+// Validate checks the config, all the errors are reported at once
+func (c *Config) Validate() error {
+	var errs []string
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if c.Mode < model.RegistryMethodServiceEntry || c.Mode > model.RegistryMethodServiceImport {
+		addErr("mode: unknown registry method %v", c.Mode)
+	}
+	if c.ConfigRootNamespace == "" {
+		addErr("configRootNamespace: should not be empty")
+	}
+	switch polaris.ConflictPolicy(c.ConflictPolicy) {
+	case polaris.ConflictPolicyMerge, polaris.ConflictPolicyPriority:
+	default:
+		addErr("conflictPolicy: unknown policy %v", c.ConflictPolicy)
+	}
+
+	if len(c.Clusters) == 0 {
+		addErr("clusters: at least one polaris cluster is required")
+	}
+	names := make(map[string]struct{}, len(c.Clusters))
+	for i, cluster := range c.Clusters {
+		if cluster.Name == "" {
+			addErr("clusters[%d].name: should not be empty", i)
+		} else if _, exists := names[cluster.Name]; exists {
+			addErr("clusters[%d].name: duplicated cluster %v", i, cluster.Name)
+		}
+		names[cluster.Name] = struct{}{}
+		if len(cluster.Addresses) == 0 {
+			addErr("clusters[%d].addresses: at least one address is required", i)
+		}
+		for j, address := range cluster.Addresses {
+			if _, _, err := net.SplitHostPort(address); err != nil {
+				addErr("clusters[%d].addresses[%d]: %v", i, j, err)
+			}
+		}
+	}
+
+	if (c.Security.CertFile == "") != (c.Security.KeyFile == "") {
+		addErr("security: certFile and keyFile should be set together")
+	}
+	if c.Security.Secret != "" && !isNamespacedName(c.Security.Secret) {
+		addErr("security.secret: should be namespace/name: %v", c.Security.Secret)
+	}
+	if c.Security.ReloadInterval.Duration < 0 {
+		addErr("security.reloadInterval: should not be negative")
+	}
+
+	if err := c.ConversionOptions().Validate(); err != nil {
+		addErr("conversion: %v", err)
+	}
+
+	if c.Register.Enabled {
+		if _, err := labels.Parse(c.Register.Selector); err != nil {
+			addErr("register.selector: %v", err)
+		}
+	}
+
+	if c.Gateway.Service != "" {
+		if !isNamespacedName(c.Gateway.Service) {
+			addErr("gateway.service: should be namespace/name: %v", c.Gateway.Service)
+		}
+		if c.Gateway.Port == "" {
+			addErr("gateway.port: should not be empty")
+		}
+		if len(c.Gateway.Hosts) == 0 {
+			addErr("gateway.hosts: at least one mesh host is required")
+		}
+		for host, service := range c.Gateway.Hosts {
+			if !isNamespacedName(service) {
+				addErr("gateway.hosts[%v]: should be <polaris-namespace>/<polaris-service>: %v", host, service)
+			}
+		}
+	}
+
+	if c.Sharding.Enabled {
+		if c.Sharding.LeaseName == "" {
+			addErr("sharding.leaseName: should not be empty")
+		}
+		if c.Sharding.Shards <= 0 {
+			addErr("sharding.shards: should be positive")
+		}
+		// every replica would be the leader and register, and the first one to stop would deregister all
+		if !c.LeaderElection.Enabled && (c.Register.Enabled || c.Gateway.Service != "") {
+			addErr("sharding: leaderElection should be enabled to register the services or the gateway")
+		}
+	}
+	if c.LeaderElection.Enabled && c.LeaderElection.LeaseName == "" {
+		addErr("leaderElection.leaseName: should not be empty")
+	}
+	if c.LeaderElection.Enabled || c.Sharding.Enabled {
+		if c.LeaderElection.RetryPeriod.Duration <= 0 {
+			addErr("leaderElection.retryPeriod: should be positive")
+		}
+		if c.LeaderElection.RenewDeadline.Duration <= c.LeaderElection.RetryPeriod.Duration {
+			addErr("leaderElection.renewDeadline: should be greater than retryPeriod")
+		}
+		if c.LeaderElection.LeaseDuration.Duration <= c.LeaderElection.RenewDeadline.Duration {
+			addErr("leaderElection.leaseDuration: should be greater than renewDeadline")
+		}
+	}
+
+	if c.Monitoring.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Monitoring.Address); err != nil {
+			addErr("monitoring.address: %v", err)
+		}
+	}
+	if c.Monitoring.StaleThreshold.Duration < 0 {
+		addErr("monitoring.staleThreshold: should not be negative")
+	}
+	if c.Monitoring.StallTimeout.Duration < 0 {
+		addErr("monitoring.stallTimeout: should not be negative")
+	}
+	if c.Monitoring.Debug && c.Monitoring.Address == "" {
+		addErr("monitoring.debug: requires monitoring.address")
+	}
+	if c.Events.DedupInterval.Duration < 0 {
+		addErr("events.dedupInterval: should not be negative")
+	}
+
+	if c.Webhook.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Webhook.Address); err != nil {
+			addErr("webhook.address: %v", err)
+		}
+		if c.Webhook.CertFile == "" || c.Webhook.KeyFile == "" {
+			addErr("webhook: certFile and keyFile are required to serve the webhooks")
+		}
+	}
+
+	// a dry-run replica must not take over the writes of the other replicas, nor register into the polaris
+	if c.DryRun {
+		if c.LeaderElection.Enabled || c.Sharding.Enabled {
+			addErr("dryRun: leaderElection and sharding should be disabled")
+		}
+		if c.Register.Enabled || c.Gateway.Service != "" {
+			addErr("dryRun: the registration of the services and the gateway should be disabled")
+		}
+	}
+
+	if c.SDK.Protocol != defaultProtocol {
+		addErr("sdk.protocol: only %v is supported", defaultProtocol)
+	}
+	for name, d := range map[string]v1.Duration{
+		"connectTimeout":         c.SDK.ConnectTimeout,
+		"messageTimeout":         c.SDK.MessageTimeout,
+		"requestTimeout":         c.SDK.RequestTimeout,
+		"retryInterval":          c.SDK.RetryInterval,
+		"serviceExpireTime":      c.SDK.ServiceExpireTime,
+		"serviceRefreshInterval": c.SDK.ServiceRefreshInterval,
+	} {
+		if d.Duration < 0 {
+			addErr("sdk.%v: should not be negative", name)
+		}
+	}
+	if c.SDK.MaxRetryTimes < 0 {
+		addErr("sdk.maxRetryTimes: should not be negative")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %v", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func isNamespacedName(s string) bool {
+	parts := strings.SplitN(s, "/", 2)
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
+}
+
+// ClusterConfigs returns the configs of the polaris clusters
+func (c *Config) ClusterConfigs() []polaris.ClusterConfig {
+	clusters := make([]polaris.ClusterConfig, 0, len(c.Clusters))
+	for _, cluster := range c.Clusters {
+		clusters = append(clusters, polaris.ClusterConfig{
+			Name:      cluster.Name,
+			Addresses: append([]string(nil), cluster.Addresses...),
+			Locality:  cluster.Locality,
+			Network:   cluster.Network,
+		})
+	}
+	return clusters
+}
+
+// SecurityOptions returns the credentials of the polaris servers
+func (c *Config) SecurityOptions() polaris.SecurityOptions {
+	return polaris.SecurityOptions{
+		TLS:            c.Security.TLS,
+		CAFile:         c.Security.CAFile,
+		CertFile:       c.Security.CertFile,
+		KeyFile:        c.Security.KeyFile,
+		TokenFile:      c.Security.TokenFile,
+		Secret:         c.Security.Secret,
+		ServerName:     c.Security.ServerName,
+		ReloadInterval: c.Security.ReloadInterval.Duration,
+	}
+}
+
+// SDKOptions returns the settings of the polaris-go sdk
+func (c *Config) SDKOptions() polaris.SDKOptions {
+	return polaris.SDKOptions{
+		Protocol:               c.SDK.Protocol,
+		ConnectTimeout:         c.SDK.ConnectTimeout.Duration,
+		MessageTimeout:         c.SDK.MessageTimeout.Duration,
+		RequestTimeout:         c.SDK.RequestTimeout.Duration,
+		MaxRetryTimes:          c.SDK.MaxRetryTimes,
+		RetryInterval:          c.SDK.RetryInterval.Duration,
+		CacheDir:               c.SDK.CacheDir,
+		ServiceExpireTime:      c.SDK.ServiceExpireTime.Duration,
+		ServiceRefreshInterval: c.SDK.ServiceRefreshInterval.Duration,
+	}
+}
+
+// ConversionOptions returns the options of the conversions
+func (c *Config) ConversionOptions() *model.ConversionOptions {
+	return &model.ConversionOptions{
+		HostnameTemplate: c.Conversion.HostnameTemplate,
+		HealthPolicy:     c.Conversion.HealthPolicy,
+		InstanceFilter:   c.Conversion.InstanceFilter,
+	}
+}
+
+// WatcherOptions returns the options of the service watcher
+func (c *Config) WatcherOptions() watcher.Options {
+	return watcher.Options{
+		Clusters:       c.ClusterConfigs(),
+		ConflictPolicy: polaris.ConflictPolicy(c.ConflictPolicy),
+		SDK:            c.SDKOptions(),
+		RegistryMethod: c.Mode,
+		ConfigRootNS:   c.ConfigRootNamespace,
+		TargetNS:       c.TargetNamespace,
+		StaleThreshold: c.Monitoring.StaleThreshold.Duration,
+		Debug:          c.Monitoring.Debug,
+		DryRun:         c.DryRun,
+		Events: events.Options{
+			Enabled:       c.Events.Enabled,
+			DedupInterval: c.Events.DedupInterval.Duration,
+		},
+	}
+}
+
+// WebhookOptions returns the options of the webhook server
+func (c *Config) WebhookOptions() webhook.Options {
+	return webhook.Options{
+		Address:  c.Webhook.Address,
+		CertFile: c.Webhook.CertFile,
+		KeyFile:  c.Webhook.KeyFile,
+	}
+}
+
+// LeaderElectionOptions returns the options of the leader election
+func (c *Config) LeaderElectionOptions() leader.Options {
+	namespace := c.LeaderElection.LeaseNamespace
+	if namespace == "" {
+		namespace = os.Getenv(podNamespaceEnv)
+	}
+	if namespace == "" {
+		namespace = c.ConfigRootNamespace
+	}
+	return leader.Options{
+		Enabled:       c.LeaderElection.Enabled,
+		Namespace:     namespace,
+		Name:          c.LeaderElection.LeaseName,
+		LeaseDuration: c.LeaderElection.LeaseDuration.Duration,
+		RenewDeadline: c.LeaderElection.RenewDeadline.Duration,
+		RetryPeriod:   c.LeaderElection.RetryPeriod.Duration,
+	}
+}
+
+// ShardOptions returns the options of the sharding
+func (c *Config) ShardOptions() leader.ShardOptions {
+	election := c.LeaderElectionOptions()
+	return leader.ShardOptions{
+		Enabled:       c.Sharding.Enabled,
+		Namespace:     election.Namespace,
+		Name:          c.Sharding.LeaseName,
+		Shards:        c.Sharding.Shards,
+		LeaseDuration: election.LeaseDuration,
+		RenewDeadline: election.RenewDeadline,
+		RetryPeriod:   election.RetryPeriod,
+	}
+}
+
+// RegisterOptions returns the options of the kubernetes service registration
+func (c *Config) RegisterOptions() register.Options {
+	return register.Options{
+		LabelSelector:    c.Register.Selector,
+		DefaultNamespace: c.Register.Namespace,
+		NamespaceMapping: c.Register.NamespaceMapping,
+	}
+}
+
+// GatewayOptions returns the options of the gateway registration
+func (c *Config) GatewayOptions() register.GatewayOptions {
+	hosts := make(map[string]register.PolarisServiceKey, len(c.Gateway.Hosts))
+	for host, service := range c.Gateway.Hosts {
+		parts := strings.SplitN(service, "/", 2)
+		if len(parts) == 2 {
+			hosts[host] = register.PolarisServiceKey{Namespace: parts[0], Service: parts[1]}
+		}
+	}
+	return register.GatewayOptions{
+		Gateway:  c.Gateway.Service,
+		PortName: c.Gateway.Port,
+		Hosts:    hosts,
+	}
+}
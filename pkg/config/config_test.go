@@ -0,0 +1,192 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/leader"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `
+mode: 2
+configRootNamespace: polaris
+targetNamespace: polaris-services
+conflictPolicy: priority
+clusters:
+- name: gz
+  addresses: [10.0.0.1:8091, 10.0.0.2:8091]
+  locality: ap-guangzhou/ap-guangzhou-3
+- name: sh
+  addresses: [10.1.0.1:8091]
+security:
+  caFile: /etc/polaris/ca.crt
+  tokenFile: /etc/polaris/token
+conversion:
+  hostnameTemplate: "{{.Service}}.{{.Namespace}}.polaris.local"
+  healthPolicy: healthy
+  instanceFilter:
+    env: prod
+register:
+  enabled: true
+  namespaceMapping:
+    default: Production
+gateway:
+  service: istio-system/istio-eastwestgateway
+  hosts:
+    reviews.default.svc.cluster.local: Production/reviews
+sdk:
+  connectTimeout: 3s
+  maxRetryTimes: 3
+  cacheDir: /var/cache/polaris
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+	cfg, err := Load(writeConfig(t, testConfig))
+	assert.Nil(err)
+	assert.Nil(cfg.Validate())
+
+	assert.Equal(model.RegistryMethodKubernetesService, cfg.Mode)
+	assert.Len(cfg.ClusterConfigs(), 2)
+	assert.Equal([]string{"10.0.0.1:8091", "10.0.0.2:8091"}, cfg.ClusterConfigs()[0].Addresses)
+	assert.True(cfg.SecurityOptions().Enabled())
+	assert.Equal(defaultReload, cfg.SecurityOptions().ReloadInterval)
+	assert.Equal(model.HealthPolicyHealthy, cfg.ConversionOptions().HealthPolicy)
+	assert.Equal("Production", cfg.RegisterOptions().NamespaceMapping["default"])
+	assert.Equal("reviews", cfg.GatewayOptions().Hosts["reviews.default.svc.cluster.local"].Service)
+	assert.Equal(defaultGatewayPort, cfg.GatewayOptions().PortName)
+
+	sdk := cfg.SDKOptions()
+	assert.Equal(defaultProtocol, sdk.Protocol)
+	assert.Equal(3*time.Second, sdk.ConnectTimeout)
+	assert.Equal(3, sdk.MaxRetryTimes)
+	assert.Equal("/var/cache/polaris", sdk.CacheDir)
+
+	// the unknown fields are rejected
+	_, err = Load(writeConfig(t, "mode: 1\nconfigRootNS: polaris\n"))
+	assert.NotNil(err)
+	_, err = Load(writeConfig(t, "sdk:\n  timeout: 1s\n"))
+	assert.NotNil(err)
+}
+
+func TestLoadDefault(t *testing.T) {
+	assert := assert.New(t)
+	cfg, err := Load("")
+	assert.Nil(err)
+	assert.NotNil(cfg.Validate())
+	cfg.SetDefaultCluster("")
+	assert.Nil(cfg.Validate())
+	assert.Equal([]string{defaultPolarisAddress}, cfg.Clusters[0].Addresses)
+	// the metrics listener and the events are opt-in
+	assert.Empty(cfg.Monitoring.Address)
+	assert.False(cfg.Events.Enabled)
+}
+
+func TestApplyEnv(t *testing.T) {
+	assert := assert.New(t)
+	env := map[string]string{
+		"POLARIS2ISTIO_MODE":                  "3",
+		"POLARIS2ISTIO_CONFIG_ROOT_NAMESPACE": "mesh",
+		"POLARIS2ISTIO_SECURITY_TLS":          "true",
+		"POLARIS2ISTIO_SDK_CONNECT_TIMEOUT":   "10s",
+		"POLARIS2ISTIO_SDK_MAX_RETRY_TIMES":   "5",
+	}
+	lookup := func(key string) (string, bool) {
+		value, exists := env[key]
+		return value, exists
+	}
+
+	cfg := Default()
+	assert.Nil(applyEnv(cfg, lookup))
+	assert.Equal(model.RegistryMethodServiceImport, cfg.Mode)
+	assert.Equal("mesh", cfg.ConfigRootNamespace)
+	assert.True(cfg.Security.TLS)
+	assert.Equal(10*time.Second, cfg.SDK.ConnectTimeout.Duration)
+	assert.Equal(5, cfg.SDK.MaxRetryTimes)
+
+	env["POLARIS2ISTIO_SDK_CONNECT_TIMEOUT"] = "10"
+	assert.NotNil(applyEnv(Default(), lookup))
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	cfg := Default()
+	cfg.Mode = 4
+	cfg.ConflictPolicy = "first"
+	cfg.Clusters = []Cluster{{Name: "gz", Addresses: []string{"10.0.0.1"}}, {Name: "gz"}}
+	cfg.Security.CertFile = "/etc/polaris/tls.crt"
+	cfg.Conversion.HostnameTemplate = "{{.Cluster}}"
+	cfg.Gateway.Service = "istio-eastwestgateway"
+	cfg.LeaderElection.Enabled = true
+	cfg.LeaderElection.LeaseDuration.Duration = cfg.LeaderElection.RenewDeadline.Duration
+	cfg.Sharding.Enabled = true
+	cfg.Sharding.Shards = 0
+	cfg.SDK.Protocol = "http"
+	cfg.Webhook.Address = ":9443"
+	cfg.DryRun = true
+
+	err := cfg.Validate()
+	assert.NotNil(err)
+	for _, field := range []string{"mode", "conflictPolicy", "clusters[0].addresses[0]", "clusters[1].name",
+		"clusters[1].addresses", "security", "conversion", "gateway.service", "gateway.hosts", "leaderElection.leaseDuration", "sharding.shards", "sdk.protocol",
+		"webhook", "dryRun"} {
+		assert.Contains(err.Error(), field+":")
+	}
+
+	cfg = Default()
+	cfg.SetDefaultCluster("127.0.0.1:8091")
+	cfg.Sharding.Enabled = true
+	assert.Nil(cfg.Validate())
+	cfg.Register.Enabled = true
+	assert.Contains(cfg.Validate().Error(), "sharding: leaderElection should be enabled")
+	cfg.LeaderElection.Enabled = true
+	assert.Nil(cfg.Validate())
+}
+
+func TestLeaderElectionOptions(t *testing.T) {
+	assert := assert.New(t)
+	cfg := Default()
+	cfg.LeaderElection.Enabled = true
+	t.Setenv(podNamespaceEnv, "")
+	assert.Equal(defaultConfigRootNS, cfg.LeaderElectionOptions().Namespace)
+
+	t.Setenv(podNamespaceEnv, "istio-system")
+	assert.Equal("istio-system", cfg.LeaderElectionOptions().Namespace)
+
+	cfg.LeaderElection.LeaseNamespace = "polaris-system"
+	opts := cfg.LeaderElectionOptions()
+	assert.Equal("polaris-system", opts.Namespace)
+	assert.Equal(leader.DefaultLeaseName, opts.Name)
+	assert.Equal(leader.DefaultLeaseDuration, opts.LeaseDuration)
+
+	shard := cfg.ShardOptions()
+	assert.Equal("polaris-system", shard.Namespace)
+	assert.Equal(leader.DefaultShardLeaseName, shard.Name)
+	assert.Equal(leader.DefaultShards, shard.Shards)
+}
@@ -0,0 +1,117 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the config, the name of a variable is
+// the upper snake case of the path of the field, such as POLARIS2ISTIO_SDK_CONNECT_TIMEOUT for sdk.connectTimeout.
+// Only the scalar fields can be overridden, the lists and maps are only configured in the file.
+const EnvPrefix = "POLARIS2ISTIO_"
+
+var durationType = reflect.TypeOf(v1.Duration{})
+
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	return applyEnvToStruct(reflect.ValueOf(cfg).Elem(), EnvPrefix, lookup)
+}
+
+func applyEnvToStruct(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		env := prefix + envName(name)
+		fv := v.Field(i)
+
+		if field.Type == durationType {
+			value, exists := lookup(env)
+			if !exists {
+				continue
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid %v: %v", env, err)
+			}
+			fv.Set(reflect.ValueOf(v1.Duration{Duration: d}))
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			if err := applyEnvToStruct(fv, env+"_", lookup); err != nil {
+				return err
+			}
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Uint:
+			value, exists := lookup(env)
+			if !exists {
+				continue
+			}
+			if err := setScalar(fv, value); err != nil {
+				return fmt.Errorf("invalid %v: %v", env, err)
+			}
+		}
+	}
+	return nil
+}
+
+func setScalar(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint:
+		u, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	}
+	return nil
+}
+
+// envName converts the camel case json name to the upper snake case, such as caFile to CA_FILE
+func envName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
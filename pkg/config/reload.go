@@ -0,0 +1,146 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+// ChangeHandler is called with the old and the new config after the config is reloaded
+type ChangeHandler func(old *Config, new *Config)
+
+// Reloader polls the config file, such as a mounted ConfigMap, and swaps the config when the file is changed.
+// The new config is validated before it is swapped, an invalid config is rejected and the old one is kept.
+type Reloader struct {
+	path     string
+	interval time.Duration
+	load     func() (*Config, error)
+
+	current  atomic.Value
+	data     []byte
+	mutex    sync.Mutex
+	handlers []ChangeHandler
+}
+
+// NewReloader creates a reloader of the config file, the load function loads and validates the config
+func NewReloader(path string, interval time.Duration, current *Config, load func() (*Config, error)) (*Reloader,
+	error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %v: %v", path, err)
+	}
+	r := &Reloader{path: path, interval: interval, load: load, data: data}
+	r.current.Store(current)
+	return r, nil
+}
+
+// Current returns the current config
+func (r *Reloader) Current() *Config {
+	return r.current.Load().(*Config)
+}
+
+// OnChange adds a handler of the config changes
+func (r *Reloader) OnChange(handler ChangeHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+// Run polls the config file until the stop channel is closed
+func (r *Reloader) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.reload(); err != nil {
+				log.Errorf("[config] failed to reload config file %v, keep using the old config: %v", r.path, err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reload loads the config if the file is changed, and returns whether the config is swapped
+func (r *Reloader) reload() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file %v: %v", r.path, err)
+	}
+	if bytes.Equal(data, r.data) {
+		return false, nil
+	}
+	// the file is not read again until it is changed, even if it is invalid
+	r.data = data
+
+	cfg, err := r.load()
+	if err != nil {
+		return false, err
+	}
+	old := r.Current()
+	if reflect.DeepEqual(old, cfg) {
+		return false, nil
+	}
+	if fields := RestartRequired(old, cfg); len(fields) > 0 {
+		log.Warnf("[config] the changes of %v are applied after polaris2istio is restarted", fields)
+	}
+	log.Infof("[config] reloaded config file %v", r.path)
+	r.current.Store(cfg)
+	for _, handler := range r.handlers {
+		handler(old, cfg)
+	}
+	return true, nil
+}
+
+// RestartRequired returns the changed fields which are not applied until polaris2istio is restarted,
+// the conversion options, the namespace mapping and the gateway hosts are applied live
+func RestartRequired(old *Config, new *Config) []string {
+	var fields []string
+	compare := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			fields = append(fields, name)
+		}
+	}
+	compare("mode", old.Mode, new.Mode)
+	compare("configRootNamespace", old.ConfigRootNamespace, new.ConfigRootNamespace)
+	compare("targetNamespace", old.TargetNamespace, new.TargetNamespace)
+	compare("conflictPolicy", old.ConflictPolicy, new.ConflictPolicy)
+	compare("clusters", old.Clusters, new.Clusters)
+	compare("dryRun", old.DryRun, new.DryRun)
+	compare("security", old.Security, new.Security)
+	compare("register.enabled", old.Register.Enabled, new.Register.Enabled)
+	compare("register.selector", old.Register.Selector, new.Register.Selector)
+	compare("gateway.service", old.Gateway.Service, new.Gateway.Service)
+	compare("gateway.port", old.Gateway.Port, new.Gateway.Port)
+	compare("leaderElection", old.LeaderElection, new.LeaderElection)
+	compare("sharding", old.Sharding, new.Sharding)
+	compare("monitoring", old.Monitoring, new.Monitoring)
+	compare("events", old.Events, new.Events)
+	compare("webhook", old.Webhook, new.Webhook)
+	compare("sdk", old.SDK, new.SDK)
+	return fields
+}
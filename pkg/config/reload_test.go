@@ -0,0 +1,80 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloader(t *testing.T) {
+	assert := assert.New(t)
+	path := writeConfig(t, testConfig)
+	load := func() (*Config, error) {
+		cfg, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return cfg, cfg.Validate()
+	}
+	current, err := load()
+	assert.Nil(err)
+
+	r, err := NewReloader(path, time.Second, current, load)
+	assert.Nil(err)
+	var changes []*Config
+	r.OnChange(func(old *Config, new *Config) {
+		assert.Equal(current, old)
+		changes = append(changes, new)
+	})
+
+	// the file is unchanged
+	swapped, err := r.reload()
+	assert.Nil(err)
+	assert.False(swapped)
+
+	assert.Nil(ioutil.WriteFile(path, []byte(testConfig+"  serviceRefreshInterval: 1m\n"), 0600))
+	swapped, err = r.reload()
+	assert.Nil(err)
+	assert.True(swapped)
+	assert.Len(changes, 1)
+	assert.Equal(time.Minute, r.Current().SDK.ServiceRefreshInterval.Duration)
+
+	// the invalid config is rejected, and the old one is kept
+	assert.Nil(ioutil.WriteFile(path, []byte(testConfig+"  unknown: 1\n"), 0600))
+	swapped, err = r.reload()
+	assert.NotNil(err)
+	assert.False(swapped)
+	assert.Len(changes, 1)
+	assert.Equal(time.Minute, r.Current().SDK.ServiceRefreshInterval.Duration)
+}
+
+func TestRestartRequired(t *testing.T) {
+	assert := assert.New(t)
+	old := Default()
+	new := Default()
+	new.Conversion.HealthPolicy = model.HealthPolicyHealthy
+	new.Register.NamespaceMapping = map[string]string{"default": "Production"}
+	new.Gateway.Hosts = map[string]string{"reviews.mesh": "Production/reviews"}
+	assert.Empty(RestartRequired(old, new))
+
+	new.Mode = model.RegistryMethodServiceImport
+	new.SDK.CacheDir = "/tmp/polaris"
+	assert.Equal([]string{"mode", "sdk"}, RestartRequired(old, new))
+}
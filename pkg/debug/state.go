@@ -0,0 +1,180 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+)
+
+// the results of the last writes
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	// ResultSkipped is the result when the service is written by another replica
+	ResultSkipped = "skipped"
+)
+
+// Subscription is the subscription to the polaris service
+type Subscription struct {
+	Watched bool `json:"watched"`
+	// LastAttempt is when the service was last subscribed to, it is subscribed to on every scan of the
+	// ServiceEntries, and it is a no-op once it is watched
+	LastAttempt time.Time `json:"lastAttempt"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Instances is the summary of the last InstancesResponse of the polaris service
+type Instances struct {
+	Revision string `json:"revision"`
+	// Total is the number of the instances before they are filtered, the instances registered from the mesh and
+	// the ones rejected by the conversion options are filtered out
+	Total    int       `json:"total"`
+	Filtered int       `json:"filtered"`
+	Healthy  int       `json:"healthy"`
+	Isolated int       `json:"isolated"`
+	Received time.Time `json:"received"`
+}
+
+// Write is the result of the last write of the converted configs
+type Write struct {
+	Time   time.Time `json:"time"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// State is what polaris2istio believes about a polaris service
+type State struct {
+	Namespace    string        `json:"namespace"`
+	Service      string        `json:"service"`
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Instances    *Instances    `json:"instances,omitempty"`
+	// Converted is the spec of the ServiceEntry converted from the instances, or the Service or the
+	// ServiceImport and their EndpointSlices with the other registry methods
+	Converted interface{} `json:"converted,omitempty"`
+	LastWrite *Write      `json:"lastWrite,omitempty"`
+}
+
+type key struct {
+	namespace string
+	service   string
+}
+
+// Store keeps the sync state of the polaris services for debugging
+type Store struct {
+	now func() time.Time
+
+	mutex    sync.RWMutex
+	services map[key]*State
+}
+
+// NewStore creates a store
+func NewStore() *Store {
+	return &Store{now: time.Now, services: make(map[key]*State)}
+}
+
+// Subscribed records the result of the subscription to the polaris service
+func (s *Store) Subscribed(namespace, service string, err error) {
+	s.update(namespace, service, func(state *State, now time.Time) {
+		state.Subscription = &Subscription{Watched: err == nil, LastAttempt: now, Error: errorString(err)}
+	})
+}
+
+// Received records the summary of the instances, total is the number of the instances before they are filtered
+func (s *Store) Received(namespace, service string, total int, rsp *polarismodel.InstancesResponse) {
+	instances := &Instances{Revision: rsp.GetRevision(), Total: total, Filtered: total - len(rsp.GetInstances())}
+	for _, instance := range rsp.GetInstances() {
+		if instance.IsHealthy() {
+			instances.Healthy++
+		}
+		if instance.IsIsolated() {
+			instances.Isolated++
+		}
+	}
+	s.update(namespace, service, func(state *State, now time.Time) {
+		instances.Received = now
+		state.Instances = instances
+	})
+}
+
+// Converted records the configs converted from the instances, they should not be modified afterwards
+func (s *Store) Converted(namespace, service string, converted interface{}) {
+	s.update(namespace, service, func(state *State, now time.Time) {
+		state.Converted = converted
+	})
+}
+
+// Written records the result of the write, the error is the reason if the result is skipped
+func (s *Store) Written(namespace, service, result string, err error) {
+	s.update(namespace, service, func(state *State, now time.Time) {
+		state.LastWrite = &Write{Time: now, Result: result, Error: errorString(err)}
+	})
+}
+
+func (s *Store) update(namespace, service string, set func(state *State, now time.Time)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	k := key{namespace: namespace, service: service}
+	state, exists := s.services[k]
+	if !exists {
+		state = &State{Namespace: namespace, Service: service}
+		s.services[k] = state
+	}
+	set(state, s.now())
+}
+
+// States returns the states sorted by namespace and service, filtered by the namespace and the service if they
+// are not empty
+func (s *Store) States(namespace, service string) []State {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	states := make([]State, 0, len(s.services))
+	for k, state := range s.services {
+		if (namespace != "" && k.namespace != namespace) || (service != "" && k.service != service) {
+			continue
+		}
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Namespace != states[j].Namespace {
+			return states[i].Namespace < states[j].Namespace
+		}
+		return states[i].Service < states[j].Service
+	})
+	return states
+}
+
+// ServeHTTP serves the states in JSON, filtered by the ?namespace= and ?service= parameters
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	states := s.States(r.URL.Query().Get("namespace"), r.URL.Query().Get("service"))
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(states); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,108 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/polarismesh/polaris-go/pkg/model"
+	"github.com/polarismesh/polaris-go/pkg/model/local"
+	"github.com/polarismesh/polaris-go/pkg/model/pb"
+	namingpb "github.com/polarismesh/polaris-go/pkg/model/pb/v1"
+	"github.com/stretchr/testify/assert"
+	istio "istio.io/api/networking/v1alpha3"
+)
+
+func newTestInstance(host string, healthy, isolated bool) model.Instance {
+	return pb.NewInstanceInProto(&namingpb.Instance{
+		Id:       &wrappers.StringValue{Value: host},
+		Host:     &wrappers.StringValue{Value: host},
+		Port:     &wrappers.UInt32Value{Value: 8080},
+		Weight:   &wrappers.UInt32Value{Value: 100},
+		Healthy:  &wrappers.BoolValue{Value: healthy},
+		Isolate:  &wrappers.BoolValue{Value: isolated},
+		Protocol: &wrappers.StringValue{Value: "http"},
+	}, &model.ServiceKey{Namespace: "Production", Service: "reviews"}, local.NewInstanceLocalValue())
+}
+
+func TestStore(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	store := NewStore()
+	store.now = func() time.Time { return now }
+
+	store.Subscribed("Production", "ratings", fmt.Errorf("watch all polaris clusters failed"))
+	store.Subscribed("Production", "reviews", nil)
+	store.Received("Production", "reviews", 4, &model.InstancesResponse{
+		Revision: "rev-1",
+		Instances: []model.Instance{
+			newTestInstance("10.0.0.1", true, false),
+			newTestInstance("10.0.0.2", false, false),
+			newTestInstance("10.0.0.3", true, true),
+		},
+	})
+	store.Converted("Production", "reviews", &istio.ServiceEntry{Hosts: []string{"reviews.production.polaris"}})
+	store.Written("Production", "reviews", ResultFailure, fmt.Errorf("failed to update ServiceEntry: conflict"))
+
+	states := store.States("", "")
+	assert.Len(states, 2)
+	assert.Equal("ratings", states[0].Service)
+	assert.False(states[0].Subscription.Watched)
+	assert.Equal("watch all polaris clusters failed", states[0].Subscription.Error)
+	assert.Nil(states[0].Instances)
+
+	reviews := states[1]
+	assert.True(reviews.Subscription.Watched)
+	assert.Equal(&Instances{Revision: "rev-1", Total: 4, Filtered: 1, Healthy: 2, Isolated: 1, Received: now},
+		reviews.Instances)
+	assert.Equal(&Write{Time: now, Result: ResultFailure, Error: "failed to update ServiceEntry: conflict"},
+		reviews.LastWrite)
+
+	store.Written("Production", "reviews", ResultSuccess, nil)
+	assert.Equal("", store.States("", "reviews")[0].LastWrite.Error)
+	assert.Empty(store.States("Test", ""))
+}
+
+func TestStoreServeHTTP(t *testing.T) {
+	assert := assert.New(t)
+	store := NewStore()
+	store.Subscribed("Production", "ratings", nil)
+	store.Subscribed("Production", "reviews", nil)
+	store.Converted("Production", "reviews", &istio.ServiceEntry{
+		Hosts:      []string{"reviews.production.polaris"},
+		Resolution: istio.ServiceEntry_STATIC,
+	})
+
+	recorder := httptest.NewRecorder()
+	store.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/syncz?service=reviews", nil))
+	assert.Equal("application/json", recorder.Header().Get("Content-Type"))
+	var states []map[string]interface{}
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &states))
+	assert.Len(states, 1)
+	assert.Equal(map[string]interface{}{
+		"hosts":      []interface{}{"reviews.production.polaris"},
+		"resolution": "STATIC",
+	}, states[0]["converted"])
+
+	recorder = httptest.NewRecorder()
+	store.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/syncz", nil))
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &states))
+	assert.Len(states, 2)
+}
@@ -0,0 +1,262 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dryrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/pkg/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the actions of the writes
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// serverFields are the metadata fields maintained by the kubernetes, they are not changed by the writes
+var serverFields = []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink"}
+
+// FieldChange is a field changed by an update, Old is absent for an added field and New is absent for a
+// removed field
+type FieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Change is a write which would be made without the dry run
+type Change struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	// Diff is the fields changed by the update
+	Diff []FieldChange `json:"diff,omitempty"`
+	// Object is the object created or deleted
+	Object interface{} `json:"object,omitempty"`
+}
+
+type key struct {
+	resource  string
+	namespace string
+	name      string
+}
+
+// Recorder logs the writes instead of making them, the last change of each object is kept
+type Recorder struct {
+	now func() time.Time
+
+	mutex   sync.RWMutex
+	changes map[key]*Change
+}
+
+// NewRecorder creates a recorder
+func NewRecorder() *Recorder {
+	return &Recorder{now: time.Now, changes: make(map[key]*Change)}
+}
+
+// Create records the object which would be created
+func (r *Recorder) Create(resource string, obj v1.Object) {
+	object, err := normalize(obj)
+	if err != nil {
+		log.Errorf("[dryrun] failed to record the creation of %v %v/%v: %v", resource, obj.GetNamespace(),
+			obj.GetName(), err)
+		return
+	}
+	r.record(&Change{Action: ActionCreate, Resource: resource, Namespace: obj.GetNamespace(), Name: obj.GetName(),
+		Object: object})
+}
+
+// Update records the fields which would be changed by updating the old object to the new one
+func (r *Recorder) Update(resource string, old v1.Object, new v1.Object) {
+	diff, err := Diff(old, new)
+	if err != nil {
+		log.Errorf("[dryrun] failed to record the update of %v %v/%v: %v", resource, new.GetNamespace(),
+			new.GetName(), err)
+		return
+	}
+	if len(diff) == 0 {
+		return
+	}
+	r.record(&Change{Action: ActionUpdate, Resource: resource, Namespace: new.GetNamespace(), Name: new.GetName(),
+		Diff: diff})
+}
+
+// Delete records the object which would be deleted
+func (r *Recorder) Delete(resource string, obj v1.Object) {
+	object, err := normalize(obj)
+	if err != nil {
+		log.Errorf("[dryrun] failed to record the deletion of %v %v/%v: %v", resource, obj.GetNamespace(),
+			obj.GetName(), err)
+		return
+	}
+	r.record(&Change{Action: ActionDelete, Resource: resource, Namespace: obj.GetNamespace(), Name: obj.GetName(),
+		Object: object})
+}
+
+// record keeps the change, it is only logged when it differs from the last change of the object, so that the
+// writes retried on every resync are not logged again
+func (r *Recorder) record(change *Change) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	k := key{resource: change.Resource, namespace: change.Namespace, name: change.Name}
+	if last, exists := r.changes[k]; exists && last.Action == change.Action &&
+		reflect.DeepEqual(last.Diff, change.Diff) && reflect.DeepEqual(last.Object, change.Object) {
+		return
+	}
+	change.Time = r.now()
+	r.changes[k] = change
+
+	details := change.Object
+	if change.Action == ActionUpdate {
+		details = change.Diff
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		data = []byte(err.Error())
+	}
+	log.Infof("[dryrun] would %v %v %v/%v: %s", change.Action, change.Resource, change.Namespace, change.Name, data)
+}
+
+// Changes returns the changes sorted by resource, namespace and name, filtered by the resource and the namespace
+// if they are not empty
+func (r *Recorder) Changes(resource, namespace string) []Change {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	changes := make([]Change, 0, len(r.changes))
+	for k, change := range r.changes {
+		if (resource != "" && k.resource != resource) || (namespace != "" && k.namespace != namespace) {
+			continue
+		}
+		changes = append(changes, *change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Resource != changes[j].Resource {
+			return changes[i].Resource < changes[j].Resource
+		}
+		if changes[i].Namespace != changes[j].Namespace {
+			return changes[i].Namespace < changes[j].Namespace
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// ServeHTTP serves the changes in JSON, filtered by the ?resource= and ?namespace= parameters
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	changes := r.Changes(req.URL.Query().Get("resource"), req.URL.Query().Get("namespace"))
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(changes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Diff returns the fields changed from the old object to the new one, the status and the metadata maintained by
+// the kubernetes are ignored
+func Diff(old interface{}, new interface{}) ([]FieldChange, error) {
+	o, err := normalize(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := normalize(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []FieldChange
+	diff("", o, n, &changes)
+	return changes, nil
+}
+
+// normalize converts the object to its JSON representation without the status and the server fields
+func normalize(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{})
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	delete(out, "status")
+	if metadata, ok := out["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverFields {
+			delete(metadata, field)
+		}
+	}
+	return out, nil
+}
+
+func diff(path string, old interface{}, new interface{}, changes *[]FieldChange) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(o)+len(n))
+			for k := range o {
+				keys = append(keys, k)
+			}
+			for k := range n {
+				if _, exists := o[k]; !exists {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				diff(fieldPath(path, k), o[k], n[k], changes)
+			}
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			for i := 0; i < len(o) || i < len(n); i++ {
+				var oi, ni interface{}
+				if i < len(o) {
+					oi = o[i]
+				}
+				if i < len(n) {
+					ni = n[i]
+				}
+				diff(fmt.Sprintf("%s[%d]", path, i), oi, ni, changes)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, FieldChange{Path: path, Old: old, New: new})
+	}
+}
+
+// fieldPath appends the field to the path, the fields with dots such as the annotations are quoted
+func fieldPath(path string, field string) string {
+	if strings.ContainsAny(field, "./") {
+		return fmt.Sprintf("%s[%q]", path, field)
+	}
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
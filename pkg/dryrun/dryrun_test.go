@@ -0,0 +1,107 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dryrun
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newServiceEntry(revision string, addresses ...string) *v1alpha3.ServiceEntry {
+	se := &v1alpha3.ServiceEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "test.polaris-rating",
+			Namespace:   "polaris",
+			Annotations: map[string]string{"aeraki.net/revision": revision},
+		},
+		Spec: istio.ServiceEntry{Hosts: []string{"test.polaris-rating.polaris"}},
+	}
+	for _, address := range addresses {
+		se.Spec.Endpoints = append(se.Spec.Endpoints, &istio.WorkloadEntry{Address: address})
+	}
+	return se
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+	old := newServiceEntry("rev-1", "10.0.0.1", "10.0.0.2")
+	old.ResourceVersion = "42"
+	old.Annotations["kubectl.kubernetes.io/last-applied-configuration"] = "{}"
+
+	diff, err := Diff(old, newServiceEntry("rev-2", "10.0.0.1", "10.0.0.3", "10.0.0.4"))
+	assert.Nil(err)
+	assert.Equal([]FieldChange{
+		{Path: `metadata.annotations["aeraki.net/revision"]`, Old: "rev-1", New: "rev-2"},
+		{Path: `metadata.annotations["kubectl.kubernetes.io/last-applied-configuration"]`, Old: "{}"},
+		{Path: "spec.endpoints[1].address", Old: "10.0.0.2", New: "10.0.0.3"},
+		{Path: "spec.endpoints[2]", New: map[string]interface{}{"address": "10.0.0.4"}},
+	}, diff)
+
+	diff, err = Diff(old, old)
+	assert.Nil(err)
+	assert.Empty(diff)
+}
+
+func TestRecorder(t *testing.T) {
+	assert := assert.New(t)
+	r := NewRecorder()
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	r.Update("serviceentries", newServiceEntry("rev-1"), newServiceEntry("rev-1"))
+	assert.Empty(r.Changes("", ""))
+
+	r.Update("serviceentries", newServiceEntry("rev-1"), newServiceEntry("rev-2"))
+	r.Create("workloadentries", &v1alpha3.WorkloadEntry{ObjectMeta: v1.ObjectMeta{Name: "we", Namespace: "polaris"}})
+	changes := r.Changes("", "")
+	assert.Len(changes, 2)
+	assert.Equal(ActionUpdate, changes[0].Action)
+	assert.Equal("test.polaris-rating", changes[0].Name)
+	assert.Len(changes[0].Diff, 1)
+	assert.Equal(ActionCreate, changes[1].Action)
+	assert.NotNil(changes[1].Object)
+
+	// the same change is not recorded again
+	now = now.Add(time.Minute)
+	r.Update("serviceentries", newServiceEntry("rev-1"), newServiceEntry("rev-2"))
+	assert.Equal(time.Unix(1000, 0), r.Changes("serviceentries", "")[0].Time)
+	r.Delete("serviceentries", newServiceEntry("rev-1"))
+	assert.Equal(ActionDelete, r.Changes("serviceentries", "")[0].Action)
+	assert.Equal(now, r.Changes("serviceentries", "")[0].Time)
+
+	assert.Empty(r.Changes("", "istio-system"))
+	assert.Len(r.Changes("workloadentries", "polaris"), 1)
+}
+
+func TestServeHTTP(t *testing.T) {
+	assert := assert.New(t)
+	r := NewRecorder()
+	r.Update("serviceentries", newServiceEntry("rev-1"), newServiceEntry("rev-2"))
+	r.Create("workloadentries", &v1alpha3.WorkloadEntry{ObjectMeta: v1.ObjectMeta{Name: "we", Namespace: "polaris"}})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/dryrun?resource=serviceentries", nil))
+	var changes []Change
+	assert.Nil(json.Unmarshal(rec.Body.Bytes(), &changes))
+	assert.Len(changes, 1)
+	assert.Equal(`metadata.annotations["aeraki.net/revision"]`, changes[0].Diff[0].Path)
+}
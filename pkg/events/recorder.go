@@ -0,0 +1,205 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"sync"
+	"time"
+
+	bindingscheme "github.com/aeraki-mesh/polaris2istio/pkg/client/clientset/versioned/scheme"
+	istioscheme "istio.io/client-go/pkg/clientset/versioned/scheme"
+	"istio.io/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/reference"
+)
+
+// the reasons of the events recorded on the source PolarisServiceBindings and ServiceEntries
+const (
+	// ReasonSynced is recorded when the polaris service is synced
+	ReasonSynced = "Synced"
+	// ReasonSyncFailed is recorded when the polaris service fails to be watched or synced
+	ReasonSyncFailed = "SyncFailed"
+	// ReasonConversionFailed is recorded when the config converted from the polaris service is rejected by istio
+	ReasonConversionFailed = "ConversionFailed"
+	// ReasonInvalidAnnotations is recorded when the polaris annotations of the ServiceEntry are invalid
+	ReasonInvalidAnnotations = "InvalidAnnotations"
+	// ReasonInvalidBinding is recorded when the spec of the PolarisServiceBinding is invalid
+	ReasonInvalidBinding = "InvalidBinding"
+	// ReasonPolarisServiceNotFound is recorded when the polaris service does not exist in any of the clusters
+	ReasonPolarisServiceNotFound = "PolarisServiceNotFound"
+)
+
+const (
+	component = "polaris2istio"
+	// DefaultDedupInterval is how long an identical event of the same object is not recorded again
+	DefaultDedupInterval = 10 * time.Minute
+	// the token bucket of the events of each object, it refills an event per minute after the burst
+	defaultBurst = 10
+	defaultQPS   = 1.0 / 60
+)
+
+// scheme resolves the kinds of the sources of the events
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(istioscheme.AddToScheme(scheme))
+	utilruntime.Must(bindingscheme.AddToScheme(scheme))
+}
+
+// Options are the options of the event recorder
+type Options struct {
+	Enabled bool
+	// DedupInterval is how long an identical event of the same object is not recorded again
+	DedupInterval time.Duration
+}
+
+type serviceKey struct {
+	namespace string
+	service   string
+}
+
+type eventKey struct {
+	uid       string
+	eventType string
+	reason    string
+	message   string
+}
+
+// Recorder records the sync outcomes of the polaris services as the events of their sources, the
+// PolarisServiceBindings or the ServiceEntries which declare them.
+// The identical events are deduplicated within the dedup interval, and the events of each object are rate limited
+// and aggregated by the correlator of client-go.
+type Recorder struct {
+	recorder      record.EventRecorder
+	broadcaster   record.EventBroadcaster
+	dedupInterval time.Duration
+	now           func() time.Time
+
+	mutex     sync.Mutex
+	sources   map[serviceKey]*corev1.ObjectReference
+	recorded  map[eventKey]time.Time
+	lastPrune time.Time
+	stopped   bool
+}
+
+// NewRecorder creates a recorder which writes the events to the kubernetes, it is nil if the events are disabled
+func NewRecorder(kc kubernetes.Interface, opts Options) *Recorder {
+	if !opts.Enabled {
+		return nil
+	}
+	broadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		BurstSize: defaultBurst,
+		QPS:       defaultQPS,
+	})
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kc.CoreV1().Events("")})
+	r := newRecorder(broadcaster.NewRecorder(scheme, corev1.EventSource{Component: component}), opts)
+	r.broadcaster = broadcaster
+	return r
+}
+
+func newRecorder(recorder record.EventRecorder, opts Options) *Recorder {
+	if opts.DedupInterval <= 0 {
+		opts.DedupInterval = DefaultDedupInterval
+	}
+	return &Recorder{
+		recorder:      recorder,
+		dedupInterval: opts.DedupInterval,
+		now:           time.Now,
+		sources:       make(map[serviceKey]*corev1.ObjectReference),
+		recorded:      make(map[eventKey]time.Time),
+	}
+}
+
+// Track remembers the source of the polaris service, the events of the polaris service are recorded
+// on it
+func (r *Recorder) Track(namespace, service string, source runtime.Object) {
+	ref, err := reference.GetReference(scheme, source)
+	if err != nil {
+		log.Warnf("[events] failed to get the reference of the source of %v/%v: %v", namespace, service, err)
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sources[serviceKey{namespace: namespace, service: service}] = ref
+}
+
+// Event records an event of the object unless an identical one is recorded within the dedup interval
+func (r *Recorder) Event(object runtime.Object, eventType, reason, message string) {
+	ref, err := reference.GetReference(scheme, object)
+	if err != nil {
+		log.Warnf("[events] failed to get the reference of the object: %v", err)
+		return
+	}
+	r.event(ref, eventType, reason, message)
+}
+
+// ServiceEvent records an event of the source of the polaris service, it is dropped if the source
+// is not tracked
+func (r *Recorder) ServiceEvent(namespace, service, eventType, reason, message string) {
+	r.mutex.Lock()
+	ref, exists := r.sources[serviceKey{namespace: namespace, service: service}]
+	r.mutex.Unlock()
+	if !exists {
+		log.Debugf("[events] the source of %v/%v is not tracked, drop the event %v", namespace, service, reason)
+		return
+	}
+	r.event(ref, eventType, reason, message)
+}
+
+func (r *Recorder) event(ref *corev1.ObjectReference, eventType, reason, message string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// the events are dropped after the broadcaster is shutdown, they would be sent to the closed channel
+	if r.stopped {
+		return
+	}
+	if !r.shouldRecord(eventKey{uid: string(ref.UID), eventType: eventType, reason: reason, message: message}) {
+		return
+	}
+	r.recorder.Event(ref, eventType, reason, message)
+}
+
+// shouldRecord returns whether the event is not recorded within the dedup interval, it is called with the mutex held
+func (r *Recorder) shouldRecord(key eventKey) bool {
+	now := r.now()
+	if now.Sub(r.lastPrune) > r.dedupInterval {
+		for k, recorded := range r.recorded {
+			if now.Sub(recorded) > r.dedupInterval {
+				delete(r.recorded, k)
+			}
+		}
+		r.lastPrune = now
+	}
+	if recorded, exists := r.recorded[key]; exists && now.Sub(recorded) <= r.dedupInterval {
+		return false
+	}
+	r.recorded[key] = now
+	return true
+}
+
+// Shutdown stops writing the events
+func (r *Recorder) Shutdown() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stopped = true
+	if r.broadcaster != nil {
+		r.broadcaster.Shutdown()
+	}
+}
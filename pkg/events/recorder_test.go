@@ -0,0 +1,105 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/apis/polaris/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func newTestServiceEntry(name string) *v1alpha3.ServiceEntry {
+	return &v1alpha3.ServiceEntry{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "polaris",
+		UID:       types.UID("uid-" + name),
+	}}
+}
+
+// recorded returns the events recorded by the fake recorder
+func recorded(fake *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-fake.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestRecorderDedup(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	fake := record.NewFakeRecorder(10)
+	recorder := newRecorder(fake, Options{Enabled: true, DedupInterval: time.Minute})
+	recorder.now = func() time.Time { return now }
+
+	se := newTestServiceEntry("reviews")
+	recorder.Event(se, corev1.EventTypeWarning, ReasonInvalidAnnotations, "aeraki.net/polarisService is missing")
+	recorder.Event(se, corev1.EventTypeWarning, ReasonInvalidAnnotations, "aeraki.net/polarisService is missing")
+	// the same event of another object is not a duplicate
+	recorder.Event(newTestServiceEntry("ratings"), corev1.EventTypeWarning, ReasonInvalidAnnotations,
+		"aeraki.net/polarisService is missing")
+	assert.Equal([]string{
+		"Warning InvalidAnnotations aeraki.net/polarisService is missing",
+		"Warning InvalidAnnotations aeraki.net/polarisService is missing",
+	}, recorded(fake))
+
+	now = now.Add(2 * time.Minute)
+	recorder.Event(se, corev1.EventTypeWarning, ReasonInvalidAnnotations, "aeraki.net/polarisService is missing")
+	assert.Len(recorded(fake), 1)
+
+	recorder.Shutdown()
+	recorder.Event(se, corev1.EventTypeWarning, ReasonSyncFailed, "conflict")
+	assert.Empty(recorded(fake))
+}
+
+func TestRecorderServiceEvent(t *testing.T) {
+	assert := assert.New(t)
+	fake := record.NewFakeRecorder(10)
+	recorder := newRecorder(fake, Options{Enabled: true})
+
+	recorder.ServiceEvent("Production", "reviews", corev1.EventTypeNormal, ReasonSynced, "Synced revision rev-1")
+	assert.Empty(recorded(fake))
+
+	recorder.Track("Production", "reviews", newTestServiceEntry("reviews"))
+	recorder.ServiceEvent("Production", "reviews", corev1.EventTypeNormal, ReasonSynced, "Synced revision rev-1")
+	recorder.ServiceEvent("Production", "reviews", corev1.EventTypeNormal, ReasonSynced, "Synced revision rev-1")
+	recorder.ServiceEvent("Production", "reviews", corev1.EventTypeWarning, ReasonPolarisServiceNotFound,
+		"Polaris service Production/reviews is not found in any of the polaris clusters")
+	assert.Equal([]string{
+		"Normal Synced Synced revision rev-1",
+		"Warning PolarisServiceNotFound Polaris service Production/reviews is not found in any of the polaris clusters",
+	}, recorded(fake))
+	assert.Equal("ServiceEntry", recorder.sources[serviceKey{namespace: "Production", service: "reviews"}].Kind)
+
+	recorder.Track("Production", "ratings", &v1alpha1.PolarisServiceBinding{ObjectMeta: metav1.ObjectMeta{
+		Name:      "ratings",
+		Namespace: "polaris",
+		UID:       types.UID("uid-ratings"),
+	}})
+	ref := recorder.sources[serviceKey{namespace: "Production", service: "ratings"}]
+	assert.Equal("PolarisServiceBinding", ref.Kind)
+	assert.Equal("polaris.aeraki.net/v1alpha1", ref.APIVersion)
+}
@@ -0,0 +1,207 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package freshness
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultThreshold is how long a service may stay out of sync before it is stale
+const DefaultThreshold = 5 * time.Minute
+
+var (
+	staleServiceDesc = prometheus.NewDesc("polaris2istio_stale_service_seconds",
+		"Seconds since the written revision of the stale polaris service last matched the observed revision.",
+		[]string{"namespace", "service"}, nil)
+	staleServicesDesc = prometheus.NewDesc("polaris2istio_stale_services",
+		"Number of the polaris services out of sync longer than the threshold.", nil, nil)
+	trackedServicesDesc = prometheus.NewDesc("polaris2istio_tracked_services",
+		"Number of the polaris services whose freshness is tracked.", nil, nil)
+)
+
+// Key is the key of a polaris service
+type Key struct {
+	Namespace string
+	Service   string
+}
+
+// Status is the freshness of a polaris service
+type Status struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	// ObservedRevision is the last revision of the polaris service observed
+	ObservedRevision string `json:"observedRevision"`
+	// WrittenRevision is the revision written to the aeraki.net/revision annotation
+	WrittenRevision string    `json:"writtenRevision"`
+	LastObserved    time.Time `json:"lastObserved"`
+	// LastMatched is when the written revision last matched the observed one, it is nil if they never matched
+	LastMatched *time.Time `json:"lastMatched,omitempty"`
+	// OutOfSyncSeconds is the seconds since they last matched, or since the service is tracked
+	// if they never matched, it is 0 if they match
+	OutOfSyncSeconds float64 `json:"outOfSyncSeconds"`
+	Stale            bool    `json:"stale"`
+}
+
+type entry struct {
+	observed     string
+	written      string
+	lastObserved time.Time
+	lastMatched  time.Time
+	// mismatchedAt is when the revisions became different, it is zero if they match
+	mismatchedAt time.Time
+}
+
+// Tracker tracks whether the revisions written to the configs keep up with the revisions observed from polaris,
+// so that a silently failed update is visible
+type Tracker struct {
+	threshold time.Duration
+	now       func() time.Time
+
+	mutex    sync.RWMutex
+	services map[Key]*entry
+}
+
+// NewTracker creates a tracker, the services out of sync longer than the threshold are stale
+func NewTracker(threshold time.Duration) *Tracker {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	return &Tracker{threshold: threshold, now: time.Now, services: make(map[Key]*entry)}
+}
+
+// Observe records the revision observed from polaris
+func (t *Tracker) Observe(namespace, service, revision string) {
+	t.update(namespace, service, func(e *entry, now time.Time) {
+		e.observed = revision
+		e.lastObserved = now
+	})
+}
+
+// Written records the revision written to the configs, or already in the configs
+func (t *Tracker) Written(namespace, service, revision string) {
+	t.update(namespace, service, func(e *entry, now time.Time) {
+		e.written = revision
+	})
+}
+
+func (t *Tracker) update(namespace, service string, set func(e *entry, now time.Time)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := t.now()
+	key := Key{Namespace: namespace, Service: service}
+	e, exists := t.services[key]
+	if !exists {
+		e = &entry{mismatchedAt: now}
+		t.services[key] = e
+	}
+	set(e, now)
+	if e.observed != "" && e.observed == e.written {
+		e.lastMatched = now
+		e.mismatchedAt = time.Time{}
+	} else if e.mismatchedAt.IsZero() {
+		e.mismatchedAt = now
+	}
+}
+
+// Forget stops tracking the service, such as when it is written by another replica
+func (t *Tracker) Forget(namespace, service string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.services, Key{Namespace: namespace, Service: service})
+}
+
+// Services returns the tracked services
+func (t *Tracker) Services() []Key {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	keys := make([]Key, 0, len(t.services))
+	for key := range t.services {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Statuses returns the freshness of the services sorted by namespace and service, only the stale ones if staleOnly
+func (t *Tracker) Statuses(staleOnly bool) []Status {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	now := t.now()
+	statuses := make([]Status, 0, len(t.services))
+	for key, e := range t.services {
+		status := Status{
+			Namespace:        key.Namespace,
+			Service:          key.Service,
+			ObservedRevision: e.observed,
+			WrittenRevision:  e.written,
+			LastObserved:     e.lastObserved,
+		}
+		if !e.lastMatched.IsZero() {
+			lastMatched := e.lastMatched
+			status.LastMatched = &lastMatched
+		}
+		if !e.mismatchedAt.IsZero() {
+			status.OutOfSyncSeconds = now.Sub(e.mismatchedAt).Seconds()
+			status.Stale = now.Sub(e.mismatchedAt) > t.threshold
+		}
+		if staleOnly && !status.Stale {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Namespace != statuses[j].Namespace {
+			return statuses[i].Namespace < statuses[j].Namespace
+		}
+		return statuses[i].Service < statuses[j].Service
+	})
+	return statuses
+}
+
+// ServeHTTP serves the stale services in JSON, or all the tracked services with ?all=true
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	statuses := t.Statuses(r.URL.Query().Get("all") != "true")
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(statuses)
+}
+
+// Describe implements prometheus.Collector
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- staleServiceDesc
+	ch <- staleServicesDesc
+	ch <- trackedServicesDesc
+}
+
+// Collect implements prometheus.Collector, only the stale services are exported one by one
+// to bound the cardinality
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	stale := t.Statuses(true)
+	for _, status := range stale {
+		ch <- prometheus.MustNewConstMetric(staleServiceDesc, prometheus.GaugeValue, status.OutOfSyncSeconds,
+			status.Namespace, status.Service)
+	}
+	ch <- prometheus.MustNewConstMetric(staleServicesDesc, prometheus.GaugeValue, float64(len(stale)))
+	t.mutex.RLock()
+	tracked := len(t.services)
+	t.mutex.RUnlock()
+	ch <- prometheus.MustNewConstMetric(trackedServicesDesc, prometheus.GaugeValue, float64(tracked))
+}
@@ -0,0 +1,94 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package freshness
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	tracker := NewTracker(time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	tracker.Observe("Production", "reviews", "rev-1")
+	tracker.Written("Production", "reviews", "rev-1")
+	tracker.Observe("Production", "ratings", "rev-1")
+	assert.Empty(tracker.Statuses(true))
+	assert.Len(tracker.Statuses(false), 2)
+
+	// the update of reviews silently fails, and ratings is never written
+	now = now.Add(10 * time.Second)
+	tracker.Observe("Production", "reviews", "rev-2")
+	now = now.Add(70 * time.Second)
+	stale := tracker.Statuses(true)
+	assert.Len(stale, 2)
+	assert.Equal("ratings", stale[0].Service)
+	assert.Nil(stale[0].LastMatched)
+	assert.Equal(80.0, stale[0].OutOfSyncSeconds)
+	assert.Equal("reviews", stale[1].Service)
+	assert.Equal("rev-2", stale[1].ObservedRevision)
+	assert.Equal("rev-1", stale[1].WrittenRevision)
+	assert.NotNil(stale[1].LastMatched)
+	assert.Equal(70.0, stale[1].OutOfSyncSeconds)
+
+	tracker.Written("Production", "reviews", "rev-2")
+	tracker.Forget("Production", "ratings")
+	assert.Empty(tracker.Statuses(true))
+	assert.Equal(0.0, tracker.Statuses(false)[0].OutOfSyncSeconds)
+}
+
+func TestTrackerExport(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	tracker := NewTracker(time.Minute)
+	tracker.now = func() time.Time { return now }
+	tracker.Observe("Production", "reviews", "rev-1")
+	tracker.Observe("Production", "ratings", "rev-1")
+	tracker.Written("Production", "ratings", "rev-1")
+	now = now.Add(2 * time.Minute)
+
+	assert.Nil(testutil.CollectAndCompare(tracker, strings.NewReader(`
+# HELP polaris2istio_stale_service_seconds Seconds since the written revision of the stale polaris service last matched the observed revision.
+# TYPE polaris2istio_stale_service_seconds gauge
+polaris2istio_stale_service_seconds{namespace="Production",service="reviews"} 120
+# HELP polaris2istio_stale_services Number of the polaris services out of sync longer than the threshold.
+# TYPE polaris2istio_stale_services gauge
+polaris2istio_stale_services 1
+# HELP polaris2istio_tracked_services Number of the polaris services whose freshness is tracked.
+# TYPE polaris2istio_tracked_services gauge
+polaris2istio_tracked_services 2
+`)))
+
+	recorder := httptest.NewRecorder()
+	tracker.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/freshness", nil))
+	var statuses []Status
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &statuses))
+	assert.Len(statuses, 1)
+	assert.True(statuses[0].Stale)
+
+	recorder = httptest.NewRecorder()
+	tracker.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/freshness?all=true", nil))
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &statuses))
+	assert.Len(statuses, 2)
+}
@@ -0,0 +1,217 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStallTimeout is how long an event is handled before the loop handling it is stalled
+const DefaultStallTimeout = 5 * time.Minute
+
+// the readiness conditions of polaris2istio
+const (
+	// ConditionServiceEntries is met when the matched ServiceEntries are listed for the first time
+	ConditionServiceEntries = "serviceentries"
+	// ConditionInitialSync is met when all the matched services are watched and synced for the first time
+	ConditionInitialSync = "initialsync"
+)
+
+// Default is the checker of polaris2istio
+var Default = NewChecker()
+
+type heartbeat struct {
+	last    time.Time
+	timeout time.Duration
+}
+
+type busy struct {
+	name  string
+	since time.Time
+}
+
+// Checker checks the readiness and the liveness of polaris2istio. It is ready when all the readiness checks pass
+// and all the conditions are met, and it is live unless a loop stops beating, handles an event too long, or exits
+// unexpectedly.
+type Checker struct {
+	now   func() time.Time
+	mutex sync.RWMutex
+
+	stallTimeout time.Duration
+
+	checks     map[string]func() error
+	conditions map[string]bool
+
+	heartbeats map[string]*heartbeat
+	busy       map[uint64]*busy
+	nextBusy   uint64
+	failures   map[string]error
+}
+
+// NewChecker creates a checker
+func NewChecker() *Checker {
+	return &Checker{
+		now:          time.Now,
+		stallTimeout: DefaultStallTimeout,
+		checks:       make(map[string]func() error),
+		conditions:   make(map[string]bool),
+		heartbeats:   make(map[string]*heartbeat),
+		busy:         make(map[uint64]*busy),
+		failures:     make(map[string]error),
+	}
+}
+
+// AddReadinessCheck adds a check which is run every time the readiness is probed
+func (c *Checker) AddReadinessCheck(name string, check func() error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checks[name] = check
+}
+
+// Require adds the conditions which are not ready until they are met
+func (c *Checker) Require(conditions ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, condition := range conditions {
+		if _, exists := c.conditions[condition]; !exists {
+			c.conditions[condition] = false
+		}
+	}
+}
+
+// Met marks the condition met, a condition stays met once it is met
+func (c *Checker) Met(condition string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conditions[condition] = true
+}
+
+// Ready returns why polaris2istio is not ready, it is nil if it is ready
+func (c *Checker) Ready() error {
+	c.mutex.RLock()
+	var reasons []string
+	for condition, met := range c.conditions {
+		if !met {
+			reasons = append(reasons, condition+": not met yet")
+		}
+	}
+	checks := make(map[string]func() error, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mutex.RUnlock()
+
+	for name, check := range checks {
+		if err := check(); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return reasonsError(reasons)
+}
+
+// Beat reports that the loop is running, the loop is stalled if it doesn't beat again within the timeout
+func (c *Checker) Beat(name string, timeout time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.heartbeats[name] = &heartbeat{last: c.now(), timeout: timeout}
+}
+
+// SetStallTimeout sets how long an event is handled before the loop handling it is stalled
+func (c *Checker) SetStallTimeout(timeout time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if timeout > 0 {
+		c.stallTimeout = timeout
+	}
+}
+
+// Busy reports that the loop starts handling an event, the returned function is called when it is handled.
+// The loop is stalled if the event is not handled within the stall timeout.
+func (c *Checker) Busy(name string) func() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	id := c.nextBusy
+	c.nextBusy++
+	c.busy[id] = &busy{name: name, since: c.now()}
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.busy, id)
+	}
+}
+
+// Fail reports that the loop exits unexpectedly, it is not live until it is restarted
+func (c *Checker) Fail(name string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.failures[name] = err
+}
+
+// Live returns why polaris2istio is not live, it is nil if it is live
+func (c *Checker) Live() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := c.now()
+	var reasons []string
+	for name, h := range c.heartbeats {
+		if since := now.Sub(h.last); since > h.timeout {
+			reasons = append(reasons, fmt.Sprintf("%s: no heartbeat for %v", name, since.Round(time.Second)))
+		}
+	}
+	for _, b := range c.busy {
+		if since := now.Sub(b.since); since > c.stallTimeout {
+			reasons = append(reasons, fmt.Sprintf("%s: handling an event for %v", b.name, since.Round(time.Second)))
+		}
+	}
+	for name, err := range c.failures {
+		reasons = append(reasons, fmt.Sprintf("%s: %v", name, err))
+	}
+	return reasonsError(reasons)
+}
+
+func reasonsError(reasons []string) error {
+	if len(reasons) == 0 {
+		return nil
+	}
+	sort.Strings(reasons)
+	return fmt.Errorf("%s", strings.Join(reasons, "; "))
+}
+
+// ReadyzHandler serves the readiness, 200 if it is ready, otherwise 503 with the reasons
+func (c *Checker) ReadyzHandler() http.Handler {
+	return probeHandler(c.Ready)
+}
+
+// HealthzHandler serves the liveness, 200 if it is live, otherwise 503 with the reasons
+func (c *Checker) HealthzHandler() http.Handler {
+	return probeHandler(c.Live)
+}
+
+func probeHandler(probe func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := probe(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprintln(w, err.Error())
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+}
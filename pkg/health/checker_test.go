@@ -0,0 +1,100 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	assert := assert.New(t)
+	checker := NewChecker()
+	checker.Require(ConditionServiceEntries, ConditionInitialSync)
+	connected := false
+	checker.AddReadinessCheck("polaris", func() error {
+		if !connected {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	})
+
+	err := checker.Ready()
+	assert.NotNil(err)
+	assert.Equal("initialsync: not met yet; polaris: connection refused; serviceentries: not met yet", err.Error())
+
+	connected = true
+	checker.Met(ConditionServiceEntries)
+	checker.Met(ConditionInitialSync)
+	assert.Nil(checker.Ready())
+
+	// a condition stays met once it is met
+	checker.Require(ConditionInitialSync)
+	assert.Nil(checker.Ready())
+}
+
+func TestLive(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	checker := NewChecker()
+	checker.now = func() time.Time { return now }
+	checker.SetStallTimeout(time.Minute)
+
+	checker.Beat("watcher", 30*time.Second)
+	done := checker.Busy("waitForEvents Production/reviews")
+	now = now.Add(20 * time.Second)
+	assert.Nil(checker.Live())
+
+	now = now.Add(20 * time.Second)
+	assert.Equal("watcher: no heartbeat for 40s", checker.Live().Error())
+	checker.Beat("watcher", 30*time.Second)
+	assert.Nil(checker.Live())
+
+	now = now.Add(30 * time.Second)
+	checker.Beat("watcher", 30*time.Second)
+	assert.Equal("waitForEvents Production/reviews: handling an event for 1m10s", checker.Live().Error())
+	done()
+	assert.Nil(checker.Live())
+
+	checker.Fail("waitForEvents Production/ratings", fmt.Errorf("the event channel is closed"))
+	assert.Equal("waitForEvents Production/ratings: the event channel is closed", checker.Live().Error())
+}
+
+func TestHandlers(t *testing.T) {
+	assert := assert.New(t)
+	checker := NewChecker()
+	checker.Require(ConditionInitialSync)
+
+	recorder := httptest.NewRecorder()
+	checker.ReadyzHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal("initialsync: not met yet", strings.TrimSpace(recorder.Body.String()))
+
+	checker.Met(ConditionInitialSync)
+	recorder = httptest.NewRecorder()
+	checker.ReadyzHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("ok", strings.TrimSpace(recorder.Body.String()))
+
+	recorder = httptest.NewRecorder()
+	checker.HealthzHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(http.StatusOK, recorder.Code)
+}
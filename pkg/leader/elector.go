@@ -0,0 +1,207 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"istio.io/pkg/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	// DefaultLeaseName is the default name of the lease of the leader election
+	DefaultLeaseName = "polaris2istio"
+	// DefaultLeaseDuration is how long the standbys wait before they take over an expired lease
+	DefaultLeaseDuration = 15 * time.Second
+	// DefaultRenewDeadline is how long the leader retries to renew the lease before it gives up the leadership
+	DefaultRenewDeadline = 10 * time.Second
+	// DefaultRetryPeriod is the interval to try to acquire or renew the lease
+	DefaultRetryPeriod = 2 * time.Second
+)
+
+// Options are the options of the leader election
+type Options struct {
+	// Enabled elects a leader among the replicas with a kubernetes lease,
+	// the only replica is always the leader if it is disabled
+	Enabled bool
+	// Namespace and Name are the namespace and the name of the lease
+	Namespace string
+	Name      string
+	// Identity is the unique identity of the replica, default to the hostname with a random suffix
+	Identity      string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Elector elects a leader among the replicas of polaris2istio. All the replicas watch the polaris services
+// to keep their caches warm, but only the leader writes to the kubernetes and the polaris.
+type Elector struct {
+	opts    Options
+	kc      kubernetes.Interface
+	leading int32
+
+	// mutex is held while the callbacks are running, so that Run waits for them before it returns
+	mutex     sync.Mutex
+	callbacks []func()
+	stopped   bool
+}
+
+// NewElector creates a leader elector
+func NewElector(opts Options) (*Elector, error) {
+	if !opts.Enabled {
+		return newElector(nil, opts)
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	kc, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newElector(kc, opts)
+}
+
+func newElector(kc kubernetes.Interface, opts Options) (*Elector, error) {
+	if opts.Enabled {
+		if opts.Namespace == "" || opts.Name == "" {
+			return nil, fmt.Errorf("the namespace and the name of the lease are required")
+		}
+		if opts.Identity == "" {
+			identity, err := defaultIdentity()
+			if err != nil {
+				return nil, err
+			}
+			opts.Identity = identity
+		}
+		if opts.LeaseDuration <= 0 {
+			opts.LeaseDuration = DefaultLeaseDuration
+		}
+		if opts.RenewDeadline <= 0 {
+			opts.RenewDeadline = DefaultRenewDeadline
+		}
+		if opts.RetryPeriod <= 0 {
+			opts.RetryPeriod = DefaultRetryPeriod
+		}
+	}
+	return &Elector{opts: opts, kc: kc}, nil
+}
+
+// defaultIdentity is the hostname, which is the pod name, with a random suffix
+func defaultIdentity() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %v", err)
+	}
+	return hostname + "_" + string(uuid.NewUUID()), nil
+}
+
+// IsLeader returns whether this replica is the leader
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leading) == 1
+}
+
+// OnStartedLeading adds a callback which is called when this replica becomes the leader
+func (e *Elector) OnStartedLeading(callback func()) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.callbacks = append(e.callbacks, callback)
+}
+
+// Run campaigns for the leadership until the stop channel is closed or the leadership is lost.
+// The lease is released when the stop channel is closed, so that a standby takes over at once.
+// It returns after the callbacks are finished.
+func (e *Elector) Run(stop <-chan struct{}) {
+	defer func() {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		e.stopped = true
+		atomic.StoreInt32(&e.leading, 0)
+	}()
+
+	if !e.opts.Enabled {
+		e.startLeading()
+		<-stop
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  v1.ObjectMeta{Namespace: e.opts.Namespace, Name: e.opts.Name},
+			Client:     e.kc.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: e.opts.Identity},
+		},
+		LeaseDuration:   e.opts.LeaseDuration,
+		RenewDeadline:   e.opts.RenewDeadline,
+		RetryPeriod:     e.opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.opts.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				e.startLeading()
+			},
+			OnStoppedLeading: func() {
+				if e.IsLeader() {
+					log.Warnf("[leader] %v stopped leading %v/%v", e.opts.Identity, e.opts.Namespace, e.opts.Name)
+				}
+				atomic.StoreInt32(&e.leading, 0)
+			},
+			OnNewLeader: func(identity string) {
+				log.Infof("[leader] the leader of %v/%v is %v", e.opts.Namespace, e.opts.Name, identity)
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("[leader] invalid leader election config: %v", err)
+		return
+	}
+	log.Infof("[leader] %v is campaigning for %v/%v", e.opts.Identity, e.opts.Namespace, e.opts.Name)
+	le.Run(ctx)
+}
+
+func (e *Elector) startLeading() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.stopped {
+		return
+	}
+	atomic.StoreInt32(&e.leading, 1)
+	log.Infof("[leader] %v started leading", e.opts.Identity)
+	for _, callback := range e.callbacks {
+		callback()
+	}
+}
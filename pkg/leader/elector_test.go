@@ -0,0 +1,102 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestElectorDisabled(t *testing.T) {
+	assert := assert.New(t)
+	e, err := NewElector(Options{})
+	assert.Nil(err)
+	started := make(chan struct{})
+	e.OnStartedLeading(func() { close(started) })
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		e.Run(stop)
+		close(done)
+	}()
+	<-started
+	assert.True(e.IsLeader())
+	close(stop)
+	<-done
+	assert.False(e.IsLeader())
+}
+
+func TestElectorFailover(t *testing.T) {
+	assert := assert.New(t)
+	kc := fake.NewSimpleClientset()
+	newTestElector := func(identity string) *Elector {
+		e, err := newElector(kc, Options{
+			Enabled:       true,
+			Namespace:     "polaris",
+			Name:          DefaultLeaseName,
+			Identity:      identity,
+			LeaseDuration: 2 * time.Second,
+			RenewDeadline: time.Second,
+			RetryPeriod:   100 * time.Millisecond,
+		})
+		assert.Nil(err)
+		return e
+	}
+
+	first := newTestElector("first")
+	firstStop := make(chan struct{})
+	firstDone := make(chan struct{})
+	go func() {
+		first.Run(firstStop)
+		close(firstDone)
+	}()
+	assert.Eventually(first.IsLeader, 5*time.Second, 50*time.Millisecond)
+
+	second := newTestElector("second")
+	secondStarted := make(chan struct{})
+	second.OnStartedLeading(func() { close(secondStarted) })
+	secondStop := make(chan struct{})
+	defer close(secondStop)
+	go second.Run(secondStop)
+	time.Sleep(500 * time.Millisecond)
+	assert.False(second.IsLeader())
+
+	// the lease is released on stop, so the standby takes over without waiting for it to expire
+	close(firstStop)
+	<-firstDone
+	assert.False(first.IsLeader())
+	select {
+	case <-secondStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the standby did not take over the lease")
+	}
+	assert.True(second.IsLeader())
+}
+
+func TestNewElectorValidation(t *testing.T) {
+	assert := assert.New(t)
+	_, err := newElector(fake.NewSimpleClientset(), Options{Enabled: true, Name: DefaultLeaseName})
+	assert.NotNil(err)
+
+	e, err := newElector(fake.NewSimpleClientset(), Options{Enabled: true, Namespace: "polaris",
+		Name: DefaultLeaseName})
+	assert.Nil(err)
+	assert.NotEmpty(e.opts.Identity)
+	assert.Equal(DefaultLeaseDuration, e.opts.LeaseDuration)
+}
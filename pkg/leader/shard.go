@@ -0,0 +1,459 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"istio.io/pkg/log"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	// DefaultShardLeaseName is the default name prefix of the sharding leases
+	DefaultShardLeaseName = "polaris2istio-shard"
+	// DefaultShards is the default number of the shards of the polaris services
+	DefaultShards = 32
+
+	shardGroupLabel = "aeraki.net/shardGroup"
+	shardRoleLabel  = "aeraki.net/shardRole"
+	shardIndexLabel = "aeraki.net/shard"
+	roleMember      = "member"
+	roleShard       = "shard"
+
+	// the member leases expired for so many lease durations are deleted
+	memberGCFactor = 10
+)
+
+// ShardOptions are the options of the sharding of the polaris services
+type ShardOptions struct {
+	// Enabled shards the polaris services across the active replicas, a replica only writes the configs of
+	// the services in its shards. The only replica owns all the services if it is disabled.
+	Enabled bool
+	// Namespace is the namespace of the leases, Name is the name prefix of the leases
+	Namespace string
+	Name      string
+	// Identity is the unique identity of the replica, default to the hostname with a random suffix
+	Identity string
+	// Shards is the number of the shards, it should be the same in all the replicas
+	Shards        int
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// observation is when the renew time of a lease was last seen changed, the leases are expired by the local clock
+// so that the clock skew between the replicas doesn't matter
+type observation struct {
+	renewTime string
+	at        time.Time
+}
+
+// Sharder shards the polaris namespace/service keys across the active replicas. Each replica keeps a member lease,
+// the shards are assigned to the live members by rendezvous hashing, so that only the shards of the joined or the left
+// replica are moved. A shard is owned through its own lease, a replica releases a shard only after it stops writing
+// it, and the new owner acquires it only after it is released or expired, so no shard is owned by two replicas.
+type Sharder struct {
+	opts ShardOptions
+	kc   kubernetes.Interface
+	now  func() time.Time
+
+	mutex     sync.RWMutex
+	owned     map[int]time.Time
+	observed  map[string]observation
+	callbacks []func()
+}
+
+// NewSharder creates a sharder
+func NewSharder(opts ShardOptions) (*Sharder, error) {
+	if !opts.Enabled {
+		return newSharder(nil, opts)
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	kc, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newSharder(kc, opts)
+}
+
+func newSharder(kc kubernetes.Interface, opts ShardOptions) (*Sharder, error) {
+	if opts.Enabled {
+		if opts.Namespace == "" || opts.Name == "" {
+			return nil, fmt.Errorf("the namespace and the name of the sharding leases are required")
+		}
+		if opts.Identity == "" {
+			identity, err := defaultIdentity()
+			if err != nil {
+				return nil, err
+			}
+			opts.Identity = identity
+		}
+		if opts.Shards <= 0 {
+			opts.Shards = DefaultShards
+		}
+		if opts.LeaseDuration <= 0 {
+			opts.LeaseDuration = DefaultLeaseDuration
+		}
+		if opts.RenewDeadline <= 0 {
+			opts.RenewDeadline = DefaultRenewDeadline
+		}
+		if opts.RetryPeriod <= 0 {
+			opts.RetryPeriod = DefaultRetryPeriod
+		}
+	}
+	return &Sharder{
+		opts:     opts,
+		kc:       kc,
+		now:      time.Now,
+		owned:    make(map[int]time.Time),
+		observed: make(map[string]observation),
+	}, nil
+}
+
+// ShardOf returns the shard of the polaris service
+func (s *Sharder) ShardOf(namespace, service string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace + "/" + service))
+	return int(h.Sum32() % uint32(s.opts.Shards))
+}
+
+// Owns returns whether this replica owns the polaris service and may write its configs
+func (s *Sharder) Owns(namespace, service string) bool {
+	if !s.opts.Enabled {
+		return true
+	}
+	return s.ownsShard(s.ShardOf(namespace, service))
+}
+
+// ownsShard returns whether the lease of the shard was renewed within the renew deadline,
+// the others don't take over the shard until the lease duration, which is longer, passes
+func (s *Sharder) ownsShard(shard int) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	renewed, exists := s.owned[shard]
+	return exists && s.now().Sub(renewed) < s.opts.RenewDeadline
+}
+
+// OwnedShards returns the shards owned by this replica
+func (s *Sharder) OwnedShards() []int {
+	var shards []int
+	for i := 0; i < s.opts.Shards; i++ {
+		if s.ownsShard(i) {
+			shards = append(shards, i)
+		}
+	}
+	return shards
+}
+
+// OnAcquired adds a callback which is called when this replica acquires new shards
+func (s *Sharder) OnAcquired(callback func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callbacks = append(s.callbacks, callback)
+}
+
+// Run keeps the membership and the shards until the stop channel is closed,
+// then the shards and the membership are released so that the others take over at once
+func (s *Sharder) Run(stop <-chan struct{}) {
+	if !s.opts.Enabled {
+		<-stop
+		return
+	}
+	log.Infof("[shard] %v joins the sharding group %v/%v", s.opts.Identity, s.opts.Namespace, s.opts.Name)
+	ticker := time.NewTicker(s.opts.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		if s.reconcile() {
+			s.mutex.RLock()
+			callbacks := append([]func(){}, s.callbacks...)
+			s.mutex.RUnlock()
+			for _, callback := range callbacks {
+				// the callbacks may take a while, the leases are renewed meanwhile
+				go callback()
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			s.leave()
+			return
+		}
+	}
+}
+
+// reconcile renews the membership, releases the shards assigned to the others and acquires the shards assigned
+// to this replica, it returns whether any shard is newly acquired
+func (s *Sharder) reconcile() bool {
+	start := s.now()
+	ctx := context.TODO()
+	if err := s.renewMember(ctx, start); err != nil {
+		log.Errorf("[shard] failed to renew the membership of %v: %v", s.opts.Identity, err)
+		return false
+	}
+	leases, err := s.kc.CoordinationV1().Leases(s.opts.Namespace).List(ctx, v1.ListOptions{
+		LabelSelector: shardGroupLabel + "=" + s.opts.Name,
+	})
+	if err != nil {
+		log.Errorf("[shard] failed to list the sharding leases: %v", err)
+		return false
+	}
+
+	var members []string
+	shards := make(map[int]*coordinationv1.Lease, s.opts.Shards)
+	observed := make(map[string]struct{}, len(leases.Items))
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		s.observe(lease, start)
+		observed[lease.Name] = struct{}{}
+		switch lease.Labels[shardRoleLabel] {
+		case roleMember:
+			if !s.expired(lease, start, 1) {
+				members = append(members, holder(lease))
+			} else if s.expired(lease, start, memberGCFactor) {
+				log.Infof("[shard] delete the expired member %v", holder(lease))
+				if err := s.kc.CoordinationV1().Leases(s.opts.Namespace).Delete(ctx, lease.Name,
+					v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+					log.Warnf("[shard] failed to delete the expired member lease %v: %v", lease.Name, err)
+				}
+			}
+		case roleShard:
+			if shard, err := strconv.Atoi(lease.Labels[shardIndexLabel]); err == nil {
+				shards[shard] = lease
+			}
+		}
+	}
+	s.mutex.Lock()
+	for name := range s.observed {
+		if _, exists := observed[name]; !exists {
+			delete(s.observed, name)
+		}
+	}
+	s.mutex.Unlock()
+	assignment := assign(members, s.opts.Shards)
+
+	acquired := false
+	for shard := 0; shard < s.opts.Shards; shard++ {
+		lease := shards[shard]
+		if assignment[shard] != s.opts.Identity {
+			// stop writing the shard before it is released to the new owner
+			s.forget(shard)
+			if lease != nil && holder(lease) == s.opts.Identity {
+				s.release(ctx, lease)
+			}
+			continue
+		}
+
+		var err error
+		switch {
+		case lease == nil:
+			err = s.createShard(ctx, shard, start)
+		case holder(lease) == s.opts.Identity || holder(lease) == "" || s.expired(lease, start, 1):
+			err = s.updateShard(ctx, lease, start)
+		default:
+			log.Debugf("[shard] wait for %v to release shard %d", holder(lease), shard)
+			continue
+		}
+		if err != nil {
+			log.Warnf("[shard] failed to acquire or renew shard %d: %v", shard, err)
+			continue
+		}
+		if s.own(shard, start) {
+			acquired = true
+		}
+	}
+	if acquired {
+		log.Infof("[shard] %v owns the shards %v", s.opts.Identity, s.OwnedShards())
+	}
+	return acquired
+}
+
+// assign assigns the shards to the members by rendezvous hashing
+func assign(members []string, shards int) []string {
+	assignment := make([]string, shards)
+	for shard := range assignment {
+		var best uint64
+		for _, member := range members {
+			// fnv is poorly mixed for the similar short keys, sha256 scores the members evenly
+			sum := sha256.Sum256([]byte(member + "/" + strconv.Itoa(shard)))
+			if score := binary.BigEndian.Uint64(sum[:8]); assignment[shard] == "" || score > best {
+				assignment[shard], best = member, score
+			}
+		}
+	}
+	return assignment
+}
+
+func holder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// observe records when the renew time of the lease is changed
+func (s *Sharder) observe(lease *coordinationv1.Lease, now time.Time) {
+	renewTime := ""
+	if lease.Spec.RenewTime != nil {
+		renewTime = lease.Spec.RenewTime.String()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if o, exists := s.observed[lease.Name]; !exists || o.renewTime != renewTime {
+		s.observed[lease.Name] = observation{renewTime: renewTime, at: now}
+	}
+}
+
+// expired returns whether the renew time of the lease is observed unchanged for the factor of the lease duration
+func (s *Sharder) expired(lease *coordinationv1.Lease, now time.Time, factor int) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	o, exists := s.observed[lease.Name]
+	return exists && now.Sub(o.at) > time.Duration(factor)*s.opts.LeaseDuration
+}
+
+// own records the renewal of the shard, and returns whether it is newly owned
+func (s *Sharder) own(shard int, renewed time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	previous, exists := s.owned[shard]
+	s.owned[shard] = renewed
+	return !exists || renewed.Sub(previous) >= s.opts.RenewDeadline
+}
+
+func (s *Sharder) forget(shard int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.owned, shard)
+}
+
+func (s *Sharder) memberLeaseName() string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s.opts.Identity))
+	return fmt.Sprintf("%s-member-%08x", s.opts.Name, h.Sum32())
+}
+
+func (s *Sharder) newLease(name string, labels map[string]string, now time.Time) *coordinationv1.Lease {
+	labels[shardGroupLabel] = s.opts.Name
+	lease := &coordinationv1.Lease{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: s.opts.Namespace, Labels: labels},
+	}
+	s.hold(lease, now)
+	return lease
+}
+
+// hold sets this replica as the holder of the lease
+func (s *Sharder) hold(lease *coordinationv1.Lease, now time.Time) {
+	identity := s.opts.Identity
+	duration := int32(s.opts.LeaseDuration / time.Second)
+	renewTime := v1.NewMicroTime(now)
+	if holder(lease) != identity {
+		transitions := int32(0)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		lease.Spec.LeaseTransitions = &transitions
+		lease.Spec.AcquireTime = &renewTime
+	}
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &renewTime
+}
+
+func (s *Sharder) renewMember(ctx context.Context, now time.Time) error {
+	leases := s.kc.CoordinationV1().Leases(s.opts.Namespace)
+	lease, err := leases.Get(ctx, s.memberLeaseName(), v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = leases.Create(ctx, s.newLease(s.memberLeaseName(), map[string]string{shardRoleLabel: roleMember},
+			now), v1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	s.hold(lease, now)
+	_, err = leases.Update(ctx, lease, v1.UpdateOptions{})
+	return err
+}
+
+func (s *Sharder) createShard(ctx context.Context, shard int, now time.Time) error {
+	lease := s.newLease(fmt.Sprintf("%s-%d", s.opts.Name, shard),
+		map[string]string{shardRoleLabel: roleShard, shardIndexLabel: strconv.Itoa(shard)}, now)
+	_, err := s.kc.CoordinationV1().Leases(s.opts.Namespace).Create(ctx, lease, v1.CreateOptions{})
+	return err
+}
+
+// updateShard acquires or renews the shard, the update fails with a conflict if the lease is changed by the others
+func (s *Sharder) updateShard(ctx context.Context, lease *coordinationv1.Lease, now time.Time) error {
+	lease = lease.DeepCopy()
+	s.hold(lease, now)
+	_, err := s.kc.CoordinationV1().Leases(s.opts.Namespace).Update(ctx, lease, v1.UpdateOptions{})
+	return err
+}
+
+func (s *Sharder) release(ctx context.Context, lease *coordinationv1.Lease) {
+	lease = lease.DeepCopy()
+	empty := ""
+	renewTime := v1.NewMicroTime(s.now())
+	lease.Spec.HolderIdentity = &empty
+	lease.Spec.RenewTime = &renewTime
+	if _, err := s.kc.CoordinationV1().Leases(s.opts.Namespace).Update(ctx, lease,
+		v1.UpdateOptions{}); err != nil {
+		// the lease expires if it fails to be released
+		log.Warnf("[shard] failed to release shard %v: %v", lease.Labels[shardIndexLabel], err)
+		return
+	}
+	log.Infof("[shard] %v released shard %v", s.opts.Identity, lease.Labels[shardIndexLabel])
+}
+
+// leave releases all the shards and deletes the membership
+func (s *Sharder) leave() {
+	ctx := context.TODO()
+	s.mutex.Lock()
+	s.owned = make(map[int]time.Time)
+	s.mutex.Unlock()
+
+	leases, err := s.kc.CoordinationV1().Leases(s.opts.Namespace).List(ctx, v1.ListOptions{
+		LabelSelector: shardGroupLabel + "=" + s.opts.Name + "," + shardRoleLabel + "=" + roleShard,
+	})
+	if err == nil {
+		for i := range leases.Items {
+			if holder(&leases.Items[i]) == s.opts.Identity {
+				s.release(ctx, &leases.Items[i])
+			}
+		}
+	} else {
+		log.Warnf("[shard] failed to list the sharding leases: %v", err)
+	}
+	if err := s.kc.CoordinationV1().Leases(s.opts.Namespace).Delete(ctx, s.memberLeaseName(),
+		v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		log.Warnf("[shard] failed to delete the member lease: %v", err)
+	}
+	log.Infof("[shard] %v left the sharding group %v/%v", s.opts.Identity, s.opts.Namespace, s.opts.Name)
+}
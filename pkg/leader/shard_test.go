@@ -0,0 +1,147 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testShards = 16
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestSharder(t *testing.T, kc kubernetes.Interface, clock *fakeClock, identity string) *Sharder {
+	s, err := newSharder(kc, ShardOptions{
+		Enabled:       true,
+		Namespace:     "polaris",
+		Name:          DefaultShardLeaseName,
+		Identity:      identity,
+		Shards:        testShards,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = clock.Now
+	return s
+}
+
+// assertExclusive checks that no shard is owned by two sharders
+func assertExclusive(t *testing.T, sharders ...*Sharder) {
+	for shard := 0; shard < testShards; shard++ {
+		var owners []string
+		for _, s := range sharders {
+			if s.ownsShard(shard) {
+				owners = append(owners, s.opts.Identity)
+			}
+		}
+		assert.LessOrEqual(t, len(owners), 1, fmt.Sprintf("shard %d is owned by %v", shard, owners))
+	}
+}
+
+func TestSharderRebalance(t *testing.T) {
+	assert := assert.New(t)
+	kc := fake.NewSimpleClientset()
+	clock := &fakeClock{now: time.Now()}
+	a := newTestSharder(t, kc, clock, "a")
+	b := newTestSharder(t, kc, clock, "b")
+	round := func(sharders ...*Sharder) {
+		clock.now = clock.now.Add(2 * time.Second)
+		for _, s := range sharders {
+			s.reconcile()
+			assertExclusive(t, a, b)
+		}
+	}
+
+	// the only replica owns all the shards
+	assert.True(a.reconcile())
+	assert.Len(a.OwnedShards(), testShards)
+	assert.True(a.Owns("Production", "reviews"))
+
+	// b joins, a releases the shards assigned to b, and b acquires them after they are released
+	round(b)
+	assert.Empty(b.OwnedShards())
+	round(a, b)
+	round(a, b)
+	assert.NotEmpty(a.OwnedShards())
+	assert.NotEmpty(b.OwnedShards())
+	assert.Len(append(a.OwnedShards(), b.OwnedShards()...), testShards)
+	assert.NotEqual(a.Owns("Production", "reviews"), b.Owns("Production", "reviews"))
+
+	// b crashes, a takes over its shards after they are expired
+	owned := b.OwnedShards()
+	for i := 0; i < 5; i++ {
+		round(a)
+	}
+	assert.Len(a.OwnedShards(), testShards-len(owned))
+	assert.Empty(b.OwnedShards())
+	for i := 0; i < 5; i++ {
+		round(a)
+	}
+	assert.Len(a.OwnedShards(), testShards)
+}
+
+func TestSharderLeave(t *testing.T) {
+	assert := assert.New(t)
+	kc := fake.NewSimpleClientset()
+	clock := &fakeClock{now: time.Now()}
+	a := newTestSharder(t, kc, clock, "a")
+	b := newTestSharder(t, kc, clock, "b")
+	for i := 0; i < 3; i++ {
+		clock.now = clock.now.Add(2 * time.Second)
+		a.reconcile()
+		b.reconcile()
+	}
+	assert.NotEmpty(b.OwnedShards())
+
+	// the shards are released when b leaves, a acquires them without waiting for them to expire
+	b.leave()
+	assert.Empty(b.OwnedShards())
+	a.reconcile()
+	assert.Len(a.OwnedShards(), testShards)
+}
+
+func TestAssign(t *testing.T) {
+	assert := assert.New(t)
+	before := assign([]string{"a", "b", "c"}, 64)
+	after := assign([]string{"a", "b", "c", "d"}, 64)
+	for shard := range before {
+		// only the shards assigned to the new member are moved
+		if after[shard] != before[shard] {
+			assert.Equal("d", after[shard])
+		}
+	}
+	assert.Equal(make([]string, 4), assign(nil, 4))
+}
+
+func TestSharderDisabled(t *testing.T) {
+	s, err := NewSharder(ShardOptions{})
+	assert.Nil(t, err)
+	assert.True(t, s.Owns("Production", "reviews"))
+}
@@ -0,0 +1,175 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const namespace = "polaris2istio"
+
+// the event types of the polaris instances
+const (
+	EventAdd    = "add"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// the results of the syncs
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	// PolarisEvents counts the instance events of the polaris services by type
+	PolarisEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "polaris_events_total",
+		Help:      "Number of the instance events of the watched polaris services by type.",
+	}, []string{"type"})
+
+	// SyncAttempts counts the syncs of the polaris services
+	SyncAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_attempts_total",
+		Help:      "Number of the attempts to sync the polaris services by registry method.",
+	}, []string{"method"})
+
+	// SyncResults counts the results of the syncs of the polaris services
+	SyncResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_results_total",
+		Help:      "Number of the results of the syncs of the polaris services by registry method and result.",
+	}, []string{"method", "result"})
+
+	// SyncDuration is the latency of the syncs of the polaris services
+	SyncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Latency of the syncs of the polaris services by registry method.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"method"})
+
+	// KubernetesWriteErrors counts the failed writes to the kubernetes
+	KubernetesWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_write_errors_total",
+		Help:      "Number of the failed writes to the kubernetes by resource and reason.",
+	}, []string{"resource", "reason"})
+
+	// ConversionErrors counts the configs converted from the polaris instances which are rejected by the validation
+	ConversionErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conversion_errors_total",
+		Help:      "Number of the converted configs rejected by the istio validation by resource.",
+	}, []string{"resource"})
+
+	// WatchedServices is the number of the watched polaris services
+	WatchedServices = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "watched_services",
+		Help:      "Number of the watched polaris services.",
+	})
+
+	// ServiceEndpoints is the number of the endpoints of each polaris service
+	ServiceEndpoints = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "service_endpoints",
+		Help:      "Number of the endpoints converted from the instances of the polaris service.",
+	}, []string{"namespace", "service"})
+
+	// PolarisConnected is whether the last request to the polaris cluster succeeded
+	PolarisConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "polaris_connected",
+		Help:      "Whether the last request to the polaris cluster succeeded, 1 for connected and 0 for disconnected.",
+	}, []string{"cluster"})
+)
+
+// Registry is the registry of the metrics of polaris2istio
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		PolarisEvents,
+		SyncAttempts,
+		SyncResults,
+		SyncDuration,
+		KubernetesWriteErrors,
+		ConversionErrors,
+		WatchedServices,
+		ServiceEndpoints,
+		PolarisConnected,
+	)
+}
+
+// Handler serves the metrics in the prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveSync records the result and the latency of a sync
+func ObserveSync(method string, start time.Time, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	SyncAttempts.WithLabelValues(method).Inc()
+	SyncResults.WithLabelValues(method, result).Inc()
+	SyncDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// KubernetesWriteError records the failed write of the kubernetes resource, and returns the error
+func KubernetesWriteError(resource string, err error) error {
+	if err != nil {
+		KubernetesWriteErrors.WithLabelValues(resource, Reason(err)).Inc()
+	}
+	return err
+}
+
+// ConversionError records the converted config of the kubernetes resource which is rejected by the validation,
+// and returns the error
+func ConversionError(resource string, err error) error {
+	if err != nil {
+		ConversionErrors.WithLabelValues(resource).Inc()
+	}
+	return err
+}
+
+// Reason returns the reason of the kubernetes api error, such as Conflict or Forbidden
+func Reason(err error) string {
+	if reason := errors.ReasonForError(err); reason != "" {
+		return string(reason)
+	}
+	return "Unknown"
+}
+
+// SetPolarisConnected records whether the polaris cluster is connected
+func SetPolarisConnected(cluster string, connected bool) {
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	PolarisConnected.WithLabelValues(cluster).Set(value)
+}
@@ -0,0 +1,54 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestObserveSync(t *testing.T) {
+	assert := assert.New(t)
+	ObserveSync("serviceentry", time.Now(), nil)
+	ObserveSync("serviceentry", time.Now(), fmt.Errorf("failed"))
+	assert.Equal(2.0, testutil.ToFloat64(SyncAttempts.WithLabelValues("serviceentry")))
+	assert.Equal(1.0, testutil.ToFloat64(SyncResults.WithLabelValues("serviceentry", ResultSuccess)))
+	assert.Equal(1.0, testutil.ToFloat64(SyncResults.WithLabelValues("serviceentry", ResultFailure)))
+	assert.Equal(1, testutil.CollectAndCount(SyncDuration))
+}
+
+func TestKubernetesWriteError(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(KubernetesWriteError("services", nil))
+
+	conflict := errors.NewConflict(schema.GroupResource{Resource: "services"}, "reviews", fmt.Errorf("changed"))
+	assert.Equal(conflict, KubernetesWriteError("services", conflict))
+	assert.NotNil(KubernetesWriteError("services", fmt.Errorf("connection refused")))
+	assert.Equal(1.0, testutil.ToFloat64(KubernetesWriteErrors.WithLabelValues("services", "Conflict")))
+	assert.Equal(1.0, testutil.ToFloat64(KubernetesWriteErrors.WithLabelValues("services", "Unknown")))
+}
+
+func TestConversionError(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(ConversionError("serviceentries", nil))
+	assert.NotNil(ConversionError("serviceentries", fmt.Errorf("invalid")))
+	assert.Equal(1.0, testutil.ToFloat64(ConversionErrors.WithLabelValues("serviceentries")))
+}
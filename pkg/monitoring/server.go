@@ -0,0 +1,77 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	"istio.io/pkg/log"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server serves the metrics of polaris2istio over http
+type Server struct {
+	mux      *http.ServeMux
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer listens on the address and serves the metrics on /metrics,
+// the other handlers can be added before it is run
+func NewServer(address string) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %v: %v", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	return &Server{
+		mux:      mux,
+		listener: listener,
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+	}, nil
+}
+
+// Handle adds the handler of the path
+func (s *Server) Handle(path string, handler http.Handler) {
+	s.mux.Handle(path, handler)
+}
+
+// Address returns the address the server listens on
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Run serves until the stop channel is closed
+func (s *Server) Run(stop <-chan struct{}) {
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := s.server.Shutdown(ctx); err != nil {
+			log.Warnf("[monitoring] failed to shutdown the server: %v", err)
+		}
+	}()
+	log.Infof("[monitoring] serving on %v", s.Address())
+	if err := s.server.Serve(s.listener); err != nil && err != http.ErrServerClosed {
+		log.Errorf("[monitoring] failed to serve on %v: %v", s.Address(), err)
+	}
+}
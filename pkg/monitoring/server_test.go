@@ -0,0 +1,45 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+	server, err := NewServer("127.0.0.1:0")
+	assert.Nil(err)
+	stop := make(chan struct{})
+	defer close(stop)
+	go server.Run(stop)
+
+	metrics.PolarisEvents.WithLabelValues(metrics.EventAdd).Inc()
+	metrics.SetPolarisConnected("gz", true)
+	rsp, err := http.Get("http://" + server.Address() + "/metrics")
+	assert.Nil(err)
+	defer rsp.Body.Close()
+	body, err := ioutil.ReadAll(rsp.Body)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, rsp.StatusCode)
+	assert.Contains(string(body), `polaris2istio_polaris_events_total{type="add"} 1`)
+	assert.Contains(string(body), `polaris2istio_polaris_connected{cluster="gz"} 1`)
+	assert.Contains(string(body), "go_goroutines")
+}
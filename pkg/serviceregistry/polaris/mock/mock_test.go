@@ -15,6 +15,7 @@
 package polarismock
 
 import (
+	"path/filepath"
 	"testing"
 
 	polarissdk "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
@@ -26,7 +27,8 @@ import (
 func TestGetAllInstance(t *testing.T) {
 	GlobalPolarisMockServer.NewServer()
 	defer GlobalPolarisMockServer.StopServer()
-	polarisclient, err := polarissdk.NewPolarisClient(GlobalPolarisMockServer.grpcListener.Addr().String())
+	polarisclient, err := polarissdk.NewPolarisClient([]string{GlobalPolarisMockServer.grpcListener.Addr().String()},
+		polarissdk.SDKOptions{CacheDir: filepath.Join(testSDKDir, "backup")})
 	if err != nil {
 		t.Errorf("NewPolarisClient failed: %v", err)
 	}
@@ -0,0 +1,82 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/apis/polaris/v1alpha1"
+)
+
+// GetPolarisInfoFromBinding get the polaris info from the spec of the PolarisServiceBinding
+func GetPolarisInfoFromBinding(binding *v1alpha1.PolarisServiceBinding) (*PolarisInfo, error) {
+	if binding.Spec.PolarisService == "" {
+		return nil, fmt.Errorf("polaris service binding should have spec.polarisService")
+	}
+	if binding.Spec.PolarisNamespace == "" {
+		return nil, fmt.Errorf("polaris service binding should have spec.polarisNamespace")
+	}
+
+	external := true
+	if binding.Spec.External != nil {
+		external = *binding.Spec.External
+	}
+	return &PolarisInfo{
+		PolarisService:   binding.Spec.PolarisService,
+		PolarisNamespace: binding.Spec.PolarisNamespace,
+		External:         strconv.FormatBool(external),
+		WorkloadEntry:    strconv.FormatBool(binding.Spec.WorkloadEntry),
+		Binding:          binding.Name,
+	}, nil
+}
+
+// GetSyncStatusFromBinding returns the sync status in the status of the binding, it is nil if the binding is
+// not synced yet
+func GetSyncStatusFromBinding(binding *v1alpha1.PolarisServiceBinding) *SyncStatus {
+	status := binding.Status
+	if status.LastSyncTime == nil {
+		return nil
+	}
+	return &SyncStatus{
+		LastSyncTime:    *status.LastSyncTime,
+		LastSuccessTime: status.LastSuccessTime,
+		Revision:        status.Revision,
+		Endpoints: EndpointCounts{
+			Total:    int(status.Endpoints.Total),
+			Healthy:  int(status.Endpoints.Healthy),
+			Filtered: int(status.Endpoints.Filtered),
+		},
+		LastError:          status.LastError,
+		ObservedGeneration: status.ObservedGeneration,
+	}
+}
+
+// BindingStatus returns the sync status as the status of the PolarisServiceBinding
+func (s *SyncStatus) BindingStatus() v1alpha1.PolarisServiceBindingStatus {
+	lastSyncTime := s.LastSyncTime
+	return v1alpha1.PolarisServiceBindingStatus{
+		ObservedGeneration: s.ObservedGeneration,
+		LastSyncTime:       &lastSyncTime,
+		LastSuccessTime:    s.LastSuccessTime,
+		Revision:           s.Revision,
+		Endpoints: v1alpha1.EndpointCounts{
+			Total:    int32(s.Endpoints.Total),
+			Healthy:  int32(s.Endpoints.Healthy),
+			Filtered: int32(s.Endpoints.Filtered),
+		},
+		LastError: s.LastError,
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/apis/polaris/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPolarisInfoFromBinding(t *testing.T) {
+	assert := assert.New(t)
+	binding := &v1alpha1.PolarisServiceBinding{
+		ObjectMeta: v1.ObjectMeta{Name: "reviews", Namespace: "polaris"},
+		Spec: v1alpha1.PolarisServiceBindingSpec{
+			PolarisNamespace: "Production",
+			PolarisService:   "reviews",
+		},
+	}
+	polarisInfo, err := GetPolarisInfoFromBinding(binding)
+	assert.Nil(err)
+	assert.Equal(&PolarisInfo{
+		PolarisService:   "reviews",
+		PolarisNamespace: "Production",
+		External:         "true",
+		WorkloadEntry:    "false",
+		Binding:          "reviews",
+	}, polarisInfo)
+	assert.False(polarisInfo.IsWorkloadEntryMode())
+
+	external := false
+	binding.Spec.External = &external
+	binding.Spec.WorkloadEntry = true
+	polarisInfo, err = GetPolarisInfoFromBinding(binding)
+	assert.Nil(err)
+	assert.Equal("false", polarisInfo.External)
+	assert.True(polarisInfo.IsWorkloadEntryMode())
+
+	binding.Spec.PolarisService = ""
+	_, err = GetPolarisInfoFromBinding(binding)
+	assert.NotNil(err)
+}
+
+func TestBindingStatus(t *testing.T) {
+	assert := assert.New(t)
+	binding := &v1alpha1.PolarisServiceBinding{}
+	assert.Nil(GetSyncStatusFromBinding(binding))
+
+	now := v1.NewTime(time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC))
+	status := &SyncStatus{
+		LastSyncTime:       now,
+		LastSuccessTime:    &now,
+		Revision:           "rev-1",
+		Endpoints:          EndpointCounts{Total: 3, Healthy: 1, Filtered: 1},
+		LastError:          "failed to update ServiceEntry: conflict",
+		ObservedGeneration: 2,
+	}
+	binding.Status = status.BindingStatus()
+	assert.Equal(v1alpha1.EndpointCounts{Total: 3, Healthy: 1, Filtered: 1}, binding.Status.Endpoints)
+	assert.Equal(status, GetSyncStatusFromBinding(binding))
+
+	// the status is not shared with the copy of the binding
+	copied := binding.DeepCopy()
+	copied.Status.LastSyncTime.Time = now.Add(time.Minute)
+	assert.True(binding.Status.LastSyncTime.Equal(&now))
+}
@@ -0,0 +1,119 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/apis/polaris/v1alpha1"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceFilter selects the polaris services by their names, the patterns are the globs of path.Match
+type ServiceFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewServiceFilter returns the filter of the services matching any of the include patterns and none of the
+// exclude patterns, all the services are included if there is no include pattern
+func NewServiceFilter(include, exclude []string) (*ServiceFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid service pattern %q: %v", pattern, err)
+		}
+	}
+	return &ServiceFilter{include: include, exclude: exclude}, nil
+}
+
+// Match returns whether the service is selected by the filter
+func (f *ServiceFilter) Match(service string) bool {
+	for _, pattern := range f.exclude {
+		if matched, _ := path.Match(pattern, service); matched {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matched, _ := path.Match(pattern, service); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// BoundServices returns the polaris services which are already declared by the ServiceEntries or the
+// PolarisServiceBindings, keyed by the namespace/service of the polaris service. The values describe what binds the
+// services.
+func BoundServices(serviceEntries []v1alpha3.ServiceEntry,
+	bindings []v1alpha1.PolarisServiceBinding) map[string]string {
+	bound := make(map[string]string)
+	for i := range serviceEntries {
+		se := &serviceEntries[i]
+		polarisInfo, err := GetPolarisInfoFromSEAnnotations(se.GetAnnotations())
+		if err != nil {
+			continue
+		}
+		bound[polarisInfo.PolarisNamespace+"/"+polarisInfo.PolarisService] =
+			fmt.Sprintf("ServiceEntry %v/%v", se.Namespace, se.Name)
+	}
+	// the ServiceEntries created for the bindings are reported as the bindings
+	for _, binding := range bindings {
+		bound[binding.Spec.PolarisNamespace+"/"+binding.Spec.PolarisService] =
+			fmt.Sprintf("PolarisServiceBinding %v/%v", binding.Namespace, binding.Name)
+	}
+	return bound
+}
+
+// NewSourceServiceEntry returns the source ServiceEntry declaring the polaris service in the namespace, as it
+// would be written by hand. Only the hosts, the location and the resolution are set, the ports and the endpoints
+// are written by the first sync.
+func NewSourceServiceEntry(polarisInfo *PolarisInfo, namespace string) *v1alpha3.ServiceEntry {
+	annotations := map[string]string{
+		"aeraki.net/polarisNamespace": polarisInfo.PolarisNamespace,
+		"aeraki.net/polarisService":   polarisInfo.PolarisService,
+		"aeraki.net/external":         polarisInfo.External,
+	}
+	if polarisInfo.IsWorkloadEntryMode() {
+		annotations["aeraki.net/workloadEntry"] = "true"
+	}
+	location := istio.ServiceEntry_MESH_EXTERNAL
+	if polarisInfo.External == "false" {
+		location = istio.ServiceEntry_MESH_INTERNAL
+	}
+
+	return &v1alpha3.ServiceEntry{
+		TypeMeta: v1.TypeMeta{APIVersion: "networking.istio.io/v1alpha3", Kind: "ServiceEntry"},
+		ObjectMeta: v1.ObjectMeta{
+			Name:      CovertServiceName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService),
+			Namespace: namespace,
+			Labels: map[string]string{
+				ManagerLabel:  AerakiFieldManager,
+				RegistryLabel: PolarisRegistry,
+			},
+			Annotations: annotations,
+		},
+		Spec: istio.ServiceEntry{
+			Hosts:      []string{CovertServiceHostname(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)},
+			Location:   location,
+			Resolution: istio.ServiceEntry_STATIC,
+		},
+	}
+}
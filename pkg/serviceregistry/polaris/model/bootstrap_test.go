@@ -0,0 +1,92 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/apis/polaris/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	filter, err := NewServiceFilter(nil, nil)
+	assert.Nil(err)
+	assert.True(filter.Match("reviews"))
+
+	filter, err = NewServiceFilter([]string{"review*", "rating"}, []string{"*-canary"})
+	assert.Nil(err)
+	assert.True(filter.Match("reviews"))
+	assert.True(filter.Match("rating"))
+	assert.False(filter.Match("reviews-canary"))
+	assert.False(filter.Match("productpage"))
+
+	_, err = NewServiceFilter(nil, []string{"[a-"})
+	assert.NotNil(err)
+}
+
+func TestBoundServices(t *testing.T) {
+	assert := assert.New(t)
+	serviceEntries := []v1alpha3.ServiceEntry{
+		{ObjectMeta: v1.ObjectMeta{Name: "reviews", Namespace: "polaris", Annotations: map[string]string{
+			"aeraki.net/polarisNamespace": "Production",
+			"aeraki.net/polarisService":   "reviews",
+		}}},
+		{ObjectMeta: v1.ObjectMeta{Name: "production.polaris-rating", Namespace: "polaris",
+			Annotations: map[string]string{
+				"aeraki.net/polarisNamespace": "Production",
+				"aeraki.net/polarisService":   "rating",
+			}}},
+		{ObjectMeta: v1.ObjectMeta{Name: "httpbin", Namespace: "polaris"}},
+	}
+	bindings := []v1alpha1.PolarisServiceBinding{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "rating", Namespace: "polaris"},
+			Spec:       v1alpha1.PolarisServiceBindingSpec{PolarisNamespace: "Production", PolarisService: "rating"},
+		},
+	}
+	assert.Equal(map[string]string{
+		"Production/reviews": "ServiceEntry polaris/reviews",
+		"Production/rating":  "PolarisServiceBinding polaris/rating",
+	}, BoundServices(serviceEntries, bindings))
+}
+
+func TestNewSourceServiceEntry(t *testing.T) {
+	assert := assert.New(t)
+
+	se := NewSourceServiceEntry(&PolarisInfo{PolarisNamespace: "Production", PolarisService: "reviews_v2",
+		External: "false", WorkloadEntry: "true"}, "polaris")
+	assert.Equal("production.polaris-reviews-v2", se.Name)
+	assert.Equal("polaris", se.Namespace)
+	assert.Equal(map[string]string{
+		"aeraki.net/polarisNamespace": "Production",
+		"aeraki.net/polarisService":   "reviews_v2",
+		"aeraki.net/external":         "false",
+		"aeraki.net/workloadEntry":    "true",
+	}, se.Annotations)
+	assert.Equal([]string{"production.polaris-reviews-v2.polaris"}, se.Spec.Hosts)
+	assert.Equal(istio.ServiceEntry_MESH_INTERNAL, se.Spec.Location)
+	assert.Nil(ValidatePolarisServiceEntry(se))
+	assert.Nil(ValidateServiceEntry(se.Name, se.Namespace, &se.Spec))
+
+	polarisInfo, err := GetPolarisInfoFromSEAnnotations(se.Annotations)
+	assert.Nil(err)
+	assert.True(polarisInfo.IsWorkloadEntryMode())
+}
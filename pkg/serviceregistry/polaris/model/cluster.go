@@ -0,0 +1,44 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"github.com/polarismesh/polaris-go/pkg/model"
+)
+
+// PolarisClusterLabel is the label of the polaris cluster which an endpoint comes from
+const PolarisClusterLabel = "aeraki.net/polarisCluster"
+
+// ClusterInstance is a polaris instance with the locality and network of the polaris cluster it comes from
+type ClusterInstance struct {
+	model.Instance
+	Cluster  string
+	Locality string
+	Network  string
+}
+
+// GetMetadata returns the metadata of the instance, with the polaris cluster label
+func (c *ClusterInstance) GetMetadata() map[string]string {
+	metadata := c.Instance.GetMetadata()
+	if c.Cluster == "" {
+		return metadata
+	}
+	out := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out[PolarisClusterLabel] = c.Cluster
+	return out
+}
@@ -16,6 +16,7 @@ package model
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"istio.io/pkg/log"
@@ -30,6 +31,10 @@ type PolarisInfo struct {
 	PolarisService   string
 	PolarisNamespace string
 	External         string
+	WorkloadEntry    string
+	// Binding is the name of the PolarisServiceBinding which declares the polaris service, it is empty if the
+	// polaris service is declared by the annotations of a ServiceEntry
+	Binding string
 }
 
 func replaceSpecialStr(s string) string {
@@ -39,9 +44,16 @@ func replaceSpecialStr(s string) string {
 	return s
 }
 
-// CovertServiceHostname covert the polaris service to host name in the polaris namespace
+// CovertServiceHostname covert the polaris service to host name with the hostname template
 func CovertServiceHostname(namespace string, name string) string {
-	return fmt.Sprintf("%s.polaris-%s.polaris", replaceSpecialStr(namespace), replaceSpecialStr(name))
+	namespace, name = replaceSpecialStr(namespace), replaceSpecialStr(name)
+	host, err := GetConversionOptions().hostname(namespace, name)
+	if err != nil {
+		log.Errorf("[CovertServiceHostname] failed to render the hostname of %v/%v, use the default: %v",
+			namespace, name, err)
+		return fmt.Sprintf("%s.polaris-%s.polaris", namespace, name)
+	}
+	return host
 }
 
 // CovertServiceName covert the polaris service to host name
@@ -70,6 +82,7 @@ func GetPolarisInfoFromSEAnnotations(annotations map[string]string) (polarisInfo
 		PolarisService:   polarisService,
 		PolarisNamespace: polarisNamespace,
 		External:         external,
+		WorkloadEntry:    annotations["aeraki.net/workloadEntry"],
 	}, nil
 }
 
@@ -84,28 +97,14 @@ func ConvertServiceEntry(rsp *model.InstancesResponse, polarisInfo *PolarisInfo)
 		location = istio.ServiceEntry_MESH_INTERNAL
 	}
 	resolution := istio.ServiceEntry_STATIC
-	ports := make(map[uint32]*istio.Port)
+	svcPorts, portNames := convertPorts(rsp)
 	workloadEntries := make([]*istio.WorkloadEntry, 0)
 	annotations := make(map[string]string)
 
 	for _, instance := range rsp.Instances {
 		log.Debugf("[ConvertServiceEntry] sync instance: [host]%v, [port]%v, [revision]%v [weight]%v [metadata]%v",
 			instance.GetHost(), instance.GetPort(), instance.GetRevision(), instance.GetWeight(), instance.GetMetadata())
-		port := convertPort(int(instance.GetPort()), instance.GetProtocol())
-
-		if svcPort, exists := ports[port.Number]; exists && svcPort.Protocol != port.Protocol {
-			log.Warnf("Service %v has two instances on same port %v but different protocols (%v, %v)",
-				rsp.GetService(), port.Number, svcPort.Protocol, port.Protocol)
-		} else {
-			ports[port.Number] = port
-		}
-
-		workloadEntries = append(workloadEntries, convertWorkloadEntry(instance))
-	}
-
-	svcPorts := make([]*istio.Port, 0, len(ports))
-	for _, port := range ports {
-		svcPorts = append(svcPorts, port)
+		workloadEntries = append(workloadEntries, convertWorkloadEntry(instance, portNames))
 	}
 
 	annotations["aeraki.net/polarisNamespace"] = rsp.GetNamespace()
@@ -121,18 +120,78 @@ func ConvertServiceEntry(rsp *model.InstancesResponse, polarisInfo *PolarisInfo)
 		Endpoints:  workloadEntries,
 	}
 
+	// the endpoints are maintained as standalone WorkloadEntries, select them instead of inlining
+	if polarisInfo.IsWorkloadEntryMode() {
+		annotations["aeraki.net/workloadEntry"] = polarisInfo.WorkloadEntry
+		out.Endpoints = nil
+		out.WorkloadSelector = convertWorkloadSelector(rsp.GetNamespace(), rsp.GetService())
+	}
+
 	return out, annotations
 }
 
-func convertWorkloadEntry(instance model.Instance) *istio.WorkloadEntry {
+// convertPorts returns the ports of the ServiceEntry of the instances sorted by number, and their names keyed by
+// number. The first protocol seen on a port wins. The port names must be unique in a ServiceEntry, so the ports
+// sharing the same protocol are named <protocol>-<port>.
+func convertPorts(rsp *model.InstancesResponse) ([]*istio.Port, map[uint32]string) {
+	ports := make(map[uint32]*istio.Port)
+	for _, instance := range rsp.Instances {
+		port := convertPort(int(instance.GetPort()), instance.GetProtocol())
+		if svcPort, exists := ports[port.Number]; exists {
+			if svcPort.Protocol != port.Protocol {
+				log.Warnf("Service %v has two instances on same port %v but different protocols (%v, %v)",
+					rsp.GetService(), port.Number, svcPort.Protocol, port.Protocol)
+			}
+			continue
+		}
+		ports[port.Number] = port
+	}
+
+	svcPorts := make([]*istio.Port, 0, len(ports))
+	counts := make(map[string]int, len(ports))
+	for _, port := range ports {
+		svcPorts = append(svcPorts, port)
+		counts[port.Name]++
+	}
+	// keep the ports in order, so that the unchanged service entries are not rewritten
+	sort.Slice(svcPorts, func(i, j int) bool {
+		return svcPorts[i].Number < svcPorts[j].Number
+	})
+
+	names := make(map[uint32]string, len(svcPorts))
+	for _, port := range svcPorts {
+		if counts[port.Name] > 1 {
+			port.Name = fmt.Sprintf("%s-%d", port.Name, port.Number)
+		}
+		names[port.Number] = port.Name
+	}
+	return svcPorts, names
+}
+
+// convertWorkloadEntry converts the instance to an endpoint, the port is named by the port names of the service
+// returned by convertPorts
+func convertWorkloadEntry(instance model.Instance, portNames map[uint32]string) *istio.WorkloadEntry {
 	addr := instance.GetHost()
 	port := convertPort(int(instance.GetPort()), instance.GetProtocol())
+	if name, exists := portNames[port.Number]; exists {
+		port.Name = name
+	}
 
-	return &istio.WorkloadEntry{
+	entry := &istio.WorkloadEntry{
 		Address: addr,
 		Ports:   map[string]uint32{port.Name: port.Number},
 		Weight:  uint32(instance.GetWeight()),
 	}
+
+	// the endpoints of a federated polaris service carry the locality and network of their polaris cluster
+	if clusterInstance, ok := instance.(*ClusterInstance); ok {
+		entry.Locality = clusterInstance.Locality
+		entry.Network = clusterInstance.Network
+		if clusterInstance.Cluster != "" {
+			entry.Labels = map[string]string{PolarisClusterLabel: clusterInstance.Cluster}
+		}
+	}
+	return entry
 }
 
 func convertPort(port int, name string) *istio.Port {
@@ -28,6 +28,7 @@ func TestGetPolarisInfoFromSEAnnotations(t *testing.T) {
 		polarisInfo *PolarisInfo
 		err         error
 	}{
+		// the aeraki.net/external annotation defaults to true
 		{map[string]string{
 			"aeraki.net/polarisNamespace": "test",
 			"aeraki.net/polarisService":   "rating",
@@ -35,6 +36,20 @@ func TestGetPolarisInfoFromSEAnnotations(t *testing.T) {
 			&PolarisInfo{
 				PolarisService:   "rating",
 				PolarisNamespace: "test",
+				External:         "true",
+			}, nil,
+		},
+		{map[string]string{
+			"aeraki.net/polarisNamespace": "test",
+			"aeraki.net/polarisService":   "rating",
+			"aeraki.net/external":         "false",
+			"aeraki.net/workloadEntry":    "true",
+		},
+			&PolarisInfo{
+				PolarisService:   "rating",
+				PolarisNamespace: "test",
+				External:         "false",
+				WorkloadEntry:    "true",
 			}, nil,
 		},
 		{map[string]string{
@@ -0,0 +1,87 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/polarismesh/polaris-go/pkg/model"
+	"github.com/polarismesh/polaris-go/pkg/model/local"
+	"github.com/polarismesh/polaris-go/pkg/model/pb"
+	namingpb "github.com/polarismesh/polaris-go/pkg/model/pb/v1"
+)
+
+// savedInstancesResponse is the InstancesResponse of the polaris-go sdk encoded by encoding/json, the instances
+// are encoded as their protos
+type savedInstancesResponse struct {
+	Service   string
+	Namespace string
+	Revision  string
+	Instances []*namingpb.Instance
+}
+
+// ParseInstancesDump parses the instances of a polaris service dumped as the JSON of the polaris HTTP API, such as
+// the discover response or the instances listed by the console API, or as an InstancesResponse of the polaris-go
+// sdk saved by encoding/json
+func ParseInstancesDump(data []byte) (*model.InstancesResponse, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid instances dump: %v", err)
+	}
+
+	var saved savedInstancesResponse
+	if _, exists := fields["Instances"]; exists {
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("invalid InstancesResponse: %v", err)
+		}
+	} else {
+		discover := &namingpb.DiscoverResponse{}
+		unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+		if err := unmarshaler.Unmarshal(bytes.NewReader(data), discover); err != nil {
+			return nil, fmt.Errorf("invalid polaris HTTP API response: %v", err)
+		}
+		saved.Service = discover.GetService().GetName().GetValue()
+		saved.Namespace = discover.GetService().GetNamespace().GetValue()
+		saved.Revision = discover.GetService().GetRevision().GetValue()
+		saved.Instances = discover.GetInstances()
+	}
+
+	// the instances listed by the console API carry their service instead of the response
+	for _, instance := range saved.Instances {
+		if saved.Service == "" {
+			saved.Service = instance.GetService().GetValue()
+		}
+		if saved.Namespace == "" {
+			saved.Namespace = instance.GetNamespace().GetValue()
+		}
+	}
+	if saved.Service == "" || saved.Namespace == "" {
+		return nil, fmt.Errorf("the polaris namespace and service are not found in the instances dump")
+	}
+
+	key := &model.ServiceKey{Namespace: saved.Namespace, Service: saved.Service}
+	instances := make([]model.Instance, 0, len(saved.Instances))
+	for _, instance := range saved.Instances {
+		instances = append(instances, pb.NewInstanceInProto(instance, key, local.NewInstanceLocalValue()))
+	}
+	return &model.InstancesResponse{
+		ServiceInfo: model.ServiceInfo{Namespace: saved.Namespace, Service: saved.Service},
+		Revision:    saved.Revision,
+		Instances:   instances,
+	}, nil
+}
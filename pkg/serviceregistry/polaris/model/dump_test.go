@@ -0,0 +1,76 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInstancesDump(t *testing.T) {
+	assert := assert.New(t)
+
+	discover := `{
+  "code": 200000,
+  "info": "execute success",
+  "type": "INSTANCE",
+  "service": {"name": "rating", "namespace": "Test", "revision": "rev-1"},
+  "instances": [
+    {"id": "a", "host": "10.0.0.1", "port": 8080, "protocol": "http", "weight": 100, "healthy": true,
+     "metadata": {"version": "v1"}, "unknownField": "ignored"},
+    {"id": "b", "host": "10.0.0.2", "port": 8080, "protocol": "http", "weight": 50, "healthy": false}
+  ]
+}`
+	rsp, err := ParseInstancesDump([]byte(discover))
+	assert.Nil(err)
+	assert.Equal("Test", rsp.GetNamespace())
+	assert.Equal("rating", rsp.GetService())
+	assert.Equal("rev-1", rsp.GetRevision())
+	assert.Len(rsp.GetInstances(), 2)
+	assert.Equal("10.0.0.1", rsp.GetInstances()[0].GetHost())
+	assert.Equal(uint32(8080), rsp.GetInstances()[0].GetPort())
+	assert.Equal(map[string]string{"version": "v1"}, rsp.GetInstances()[0].GetMetadata())
+	assert.False(rsp.GetInstances()[1].IsHealthy())
+
+	// the instances listed by the console API
+	console := `{"code": 200000, "amount": 1, "size": 1, "instances": [
+    {"id": "a", "service": "rating", "namespace": "Test", "host": "10.0.0.1", "port": 8080, "weight": 100}]}`
+	rsp, err = ParseInstancesDump([]byte(console))
+	assert.Nil(err)
+	assert.Equal("Test", rsp.GetNamespace())
+	assert.Equal("rating", rsp.GetService())
+	assert.Len(rsp.GetInstances(), 1)
+
+	// the InstancesResponse saved by encoding/json
+	saved, err := json.Marshal(newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, map[string]string{"version": "v1"})))
+	assert.Nil(err)
+	rsp, err = ParseInstancesDump(saved)
+	assert.Nil(err)
+	assert.Equal("rev-1", rsp.GetRevision())
+	assert.Equal("10.0.0.1", rsp.GetInstances()[0].GetHost())
+	assert.Equal("http", rsp.GetInstances()[0].GetProtocol())
+	assert.True(rsp.GetInstances()[0].IsHealthy())
+
+	se, _ := ConvertServiceEntry(rsp, &PolarisInfo{External: "true"})
+	assert.Equal("10.0.0.1", se.Endpoints[0].Address)
+
+	_, err = ParseInstancesDump([]byte(`{"instances": []}`))
+	assert.NotNil(err)
+	_, err = ParseInstancesDump([]byte(`[]`))
+	assert.NotNil(err)
+}
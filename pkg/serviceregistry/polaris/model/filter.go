@@ -0,0 +1,54 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"github.com/polarismesh/polaris-go/pkg/model"
+)
+
+const (
+	// RegisteredByMetadata is the metadata key of the polaris instances registered by polaris2istio
+	RegisteredByMetadata = "aeraki.net/registeredBy"
+	// RegisteredByPolaris2istio is the metadata value of the polaris instances registered by polaris2istio
+	RegisteredByPolaris2istio = "polaris2istio"
+)
+
+// IsRegisteredInstance returns whether the instance is registered into the polaris by polaris2istio,
+// such instances come from the mesh and should never be projected back into it
+func IsRegisteredInstance(instance model.Instance) bool {
+	return instance.GetMetadata()[RegisteredByMetadata] == RegisteredByPolaris2istio
+}
+
+// FilterInstances returns a copy of the response without the instances registered by polaris2istio,
+// and the instances excluded by the health policy and the instance filter of the conversion options
+func FilterInstances(rsp *model.InstancesResponse) *model.InstancesResponse {
+	out := *rsp
+	out.Instances = make([]model.Instance, 0, len(rsp.Instances))
+	for _, instance := range rsp.Instances {
+		if FilterReason(instance) != "" {
+			continue
+		}
+		out.Instances = append(out.Instances, instance)
+	}
+	return &out
+}
+
+// FilterReason returns why the instance is filtered out by FilterInstances, it is empty if the instance is kept
+func FilterReason(instance model.Instance) string {
+	if IsRegisteredInstance(instance) {
+		return "registered from the mesh"
+	}
+	return GetConversionOptions().rejectReason(instance)
+}
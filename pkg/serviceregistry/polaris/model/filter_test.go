@@ -0,0 +1,37 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterInstances(t *testing.T) {
+	assert := assert.New(t)
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil),
+		newTestInstance("Test", "rating", "10.0.0.2", 8080, true,
+			map[string]string{RegisteredByMetadata: RegisteredByPolaris2istio}),
+	)
+
+	filtered := FilterInstances(rsp)
+	assert.Len(filtered.Instances, 1)
+	assert.Equal("10.0.0.1", filtered.Instances[0].GetHost())
+	assert.Equal(rsp.GetRevision(), filtered.GetRevision())
+	assert.Len(rsp.Instances, 2)
+	assert.Equal("registered from the mesh", FilterReason(rsp.Instances[1]))
+}
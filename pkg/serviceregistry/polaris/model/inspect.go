@@ -0,0 +1,222 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/polarismesh/polaris-go/pkg/model"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+)
+
+// the problems of the endpoints found by the inspection
+const (
+	// EndpointMissing is an instance of the polaris which is not in the istio
+	EndpointMissing = "missing"
+	// EndpointStale is an endpoint in the istio which is not an instance of the polaris, or which is filtered out
+	EndpointStale = "stale"
+	// EndpointWeightMismatch is an endpoint whose weight is different from the weight of the instance
+	EndpointWeightMismatch = "weight mismatch"
+	// EndpointPortMismatch is an endpoint whose port name is different from the protocol of the instance
+	EndpointPortMismatch = "port mismatch"
+)
+
+// EndpointSide is an endpoint as it is in the polaris or in the istio
+type EndpointSide struct {
+	Ports  map[string]uint32 `json:"ports,omitempty"`
+	Weight uint32            `json:"weight"`
+	// Healthy, Isolated and Cluster are only known for the instances of the polaris
+	Healthy  *bool  `json:"healthy,omitempty"`
+	Isolated bool   `json:"isolated,omitempty"`
+	Cluster  string `json:"cluster,omitempty"`
+}
+
+// EndpointReport compares an endpoint in the polaris with the one in the istio, a side is nil if the endpoint is
+// absent from it
+type EndpointReport struct {
+	Address string        `json:"address"`
+	Port    uint32        `json:"port"`
+	Polaris *EndpointSide `json:"polaris,omitempty"`
+	Istio   *EndpointSide `json:"istio,omitempty"`
+	// Filtered is why the instance is filtered out by the conversion, it is not expected in the istio
+	Filtered string `json:"filtered,omitempty"`
+	// Problems are empty if the endpoint is synced, or if it is filtered out and absent from the istio
+	Problems []string `json:"problems,omitempty"`
+}
+
+// InspectReport compares the instances of a polaris service with the endpoints of its ServiceEntry
+type InspectReport struct {
+	PolarisNamespace string `json:"polarisNamespace"`
+	PolarisService   string `json:"polarisService"`
+	// ServiceEntry is the namespace/name of the ServiceEntry, it is empty if the ServiceEntry is not found
+	ServiceEntry    string      `json:"serviceEntry,omitempty"`
+	PolarisRevision string      `json:"polarisRevision"`
+	IstioRevision   string      `json:"istioRevision,omitempty"`
+	RevisionDrift   bool        `json:"revisionDrift"`
+	PolarisPorts    []string    `json:"polarisPorts"`
+	IstioPorts      []string    `json:"istioPorts"`
+	PortDrift       bool        `json:"portDrift"`
+	SyncStatus      *SyncStatus `json:"syncStatus,omitempty"`
+	// Endpoints are sorted by the address and the port
+	Endpoints []EndpointReport `json:"endpoints"`
+}
+
+// Problems returns the number of the endpoints with problems, and the drifts of the revision and the ports
+func (r *InspectReport) Problems() int {
+	problems := 0
+	if r.RevisionDrift {
+		problems++
+	}
+	if r.PortDrift {
+		problems++
+	}
+	for _, endpoint := range r.Endpoints {
+		if len(endpoint.Problems) > 0 {
+			problems++
+		}
+	}
+	return problems
+}
+
+// Inspect compares the instances of the polaris service with the endpoints of its ServiceEntry, the endpoints are
+// the WorkloadEntries when the ServiceEntry selects them. The ServiceEntry is nil if it is not found.
+func Inspect(rsp *model.InstancesResponse, se *v1alpha3.ServiceEntry,
+	workloadEntries []*v1alpha3.WorkloadEntry) *InspectReport {
+	report := &InspectReport{
+		PolarisNamespace: rsp.GetNamespace(),
+		PolarisService:   rsp.GetService(),
+		PolarisRevision:  rsp.GetRevision(),
+	}
+
+	endpoints := make(map[string]*EndpointReport)
+	endpointOf := func(address string, port uint32) *EndpointReport {
+		key := fmt.Sprintf("%s:%d", address, port)
+		endpoint, exists := endpoints[key]
+		if !exists {
+			endpoint = &EndpointReport{Address: address, Port: port}
+			endpoints[key] = endpoint
+		}
+		return endpoint
+	}
+
+	// the ports are named as in the ServiceEntry converted from the instances which are not filtered out
+	_, names := convertPorts(FilterInstances(rsp))
+	for _, instance := range rsp.GetInstances() {
+		endpoint := endpointOf(instance.GetHost(), instance.GetPort())
+		if endpoint.Polaris != nil {
+			continue
+		}
+		converted := convertWorkloadEntry(instance, names)
+		healthy := instance.IsHealthy()
+		endpoint.Polaris = &EndpointSide{
+			Ports:    converted.Ports,
+			Weight:   converted.Weight,
+			Healthy:  &healthy,
+			Isolated: instance.IsIsolated(),
+		}
+		if clusterInstance, ok := instance.(*ClusterInstance); ok {
+			endpoint.Polaris.Cluster = clusterInstance.Cluster
+		}
+		endpoint.Filtered = FilterReason(instance)
+	}
+
+	expected, _ := ConvertServiceEntry(FilterInstances(rsp), &PolarisInfo{External: "true"})
+	report.PolarisPorts = portNames(expected.GetPorts())
+	if se != nil {
+		report.ServiceEntry = se.Namespace + "/" + se.Name
+		report.IstioRevision = se.GetAnnotations()["aeraki.net/revision"]
+		report.RevisionDrift = report.IstioRevision != report.PolarisRevision
+		report.IstioPorts = portNames(se.Spec.GetPorts())
+		report.PortDrift = !reflect.DeepEqual(report.PolarisPorts, report.IstioPorts)
+		if status, err := GetSyncStatus(se.GetAnnotations()); err == nil {
+			report.SyncStatus = status
+		}
+
+		live := se.Spec.GetEndpoints()
+		if se.Spec.GetWorkloadSelector() != nil {
+			live = make([]*istio.WorkloadEntry, 0, len(workloadEntries))
+			for _, entry := range workloadEntries {
+				live = append(live, &entry.Spec)
+			}
+		}
+		for _, entry := range live {
+			for _, port := range endpointPorts(entry, se.Spec.GetPorts()) {
+				endpoint := endpointOf(entry.GetAddress(), port)
+				if endpoint.Istio == nil {
+					endpoint.Istio = &EndpointSide{Ports: entry.GetPorts(), Weight: entry.GetWeight()}
+				}
+			}
+		}
+	} else {
+		report.RevisionDrift = true
+		report.PortDrift = len(report.PolarisPorts) > 0
+	}
+
+	for _, endpoint := range endpoints {
+		switch {
+		case endpoint.Polaris == nil || (endpoint.Filtered != "" && endpoint.Istio != nil):
+			endpoint.Problems = append(endpoint.Problems, EndpointStale)
+		case endpoint.Filtered != "":
+		case endpoint.Istio == nil:
+			endpoint.Problems = append(endpoint.Problems, EndpointMissing)
+		default:
+			if len(endpoint.Istio.Ports) > 0 && !reflect.DeepEqual(endpoint.Polaris.Ports, endpoint.Istio.Ports) {
+				endpoint.Problems = append(endpoint.Problems, EndpointPortMismatch)
+			}
+			if endpoint.Polaris.Weight != endpoint.Istio.Weight {
+				endpoint.Problems = append(endpoint.Problems, EndpointWeightMismatch)
+			}
+		}
+		report.Endpoints = append(report.Endpoints, *endpoint)
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		if report.Endpoints[i].Address != report.Endpoints[j].Address {
+			return report.Endpoints[i].Address < report.Endpoints[j].Address
+		}
+		return report.Endpoints[i].Port < report.Endpoints[j].Port
+	})
+	return report
+}
+
+// endpointPorts returns the port numbers of the endpoint, the endpoint without the ports receives the traffic on
+// all the ports of the ServiceEntry
+func endpointPorts(entry *istio.WorkloadEntry, servicePorts []*istio.Port) []uint32 {
+	ports := make([]uint32, 0, len(entry.GetPorts()))
+	for _, port := range entry.GetPorts() {
+		ports = append(ports, port)
+	}
+	if len(ports) == 0 {
+		for _, port := range servicePorts {
+			ports = append(ports, port.GetNumber())
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
+
+// portNames returns the ports of the ServiceEntry as name:number/protocol
+func portNames(ports []*istio.Port) []string {
+	names := make([]string, 0, len(ports))
+	for _, port := range ports {
+		names = append(names, fmt.Sprintf("%s:%d/%s", port.GetName(), port.GetNumber(),
+			strings.ToUpper(port.GetProtocol())))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,93 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInspect(t *testing.T) {
+	assert := assert.New(t)
+	defer SetConversionOptions(&ConversionOptions{})
+	assert.Nil(SetConversionOptions(&ConversionOptions{HealthPolicy: HealthPolicyHealthy}))
+
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil),
+		newTestInstance("Test", "rating", "10.0.0.2", 8080, true, nil),
+		newTestInstance("Test", "rating", "10.0.0.3", 8080, true, nil),
+		newTestInstance("Test", "rating", "10.0.0.4", 8080, false, nil),
+		newTestInstance("Test", "rating", "10.0.0.5", 8080, false, nil),
+	)
+	se := &v1alpha3.ServiceEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "test.polaris-rating",
+			Namespace:   "polaris",
+			Annotations: map[string]string{"aeraki.net/revision": "rev-0"},
+		},
+		Spec: istio.ServiceEntry{
+			Ports: []*istio.Port{{Name: "http", Number: 8080, Protocol: "HTTP", TargetPort: 8080}},
+			Endpoints: []*istio.WorkloadEntry{
+				{Address: "10.0.0.1", Ports: map[string]uint32{"http": 8080}, Weight: 100},
+				{Address: "10.0.0.2", Ports: map[string]uint32{"grpc": 8080}, Weight: 50},
+				{Address: "10.0.0.4", Ports: map[string]uint32{"http": 8080}, Weight: 100},
+				{Address: "10.0.0.6", Weight: 100},
+			},
+		},
+	}
+
+	report := Inspect(rsp, se, nil)
+	assert.Equal("polaris/test.polaris-rating", report.ServiceEntry)
+	assert.Equal("rev-1", report.PolarisRevision)
+	assert.Equal("rev-0", report.IstioRevision)
+	assert.True(report.RevisionDrift)
+	assert.Equal([]string{"http:8080/HTTP"}, report.PolarisPorts)
+	assert.False(report.PortDrift)
+
+	problems := make(map[string][]string)
+	filtered := make(map[string]string)
+	for _, endpoint := range report.Endpoints {
+		problems[endpoint.Address] = endpoint.Problems
+		filtered[endpoint.Address] = endpoint.Filtered
+	}
+	assert.Equal(map[string][]string{
+		"10.0.0.1": nil,
+		"10.0.0.2": {EndpointPortMismatch, EndpointWeightMismatch},
+		"10.0.0.3": {EndpointMissing},
+		"10.0.0.4": {EndpointStale},
+		"10.0.0.5": nil,
+		"10.0.0.6": {EndpointStale},
+	}, problems)
+	assert.Equal("unhealthy", filtered["10.0.0.4"])
+	assert.Equal("unhealthy", filtered["10.0.0.5"])
+	assert.Equal(5, report.Problems())
+
+	// the endpoints are the WorkloadEntries selected by the ServiceEntry
+	se.Spec.Endpoints = nil
+	se.Spec.WorkloadSelector = convertWorkloadSelector("Test", "rating")
+	se.Annotations["aeraki.net/revision"] = "rev-1"
+	report = Inspect(rsp, se, ConvertWorkloadEntries(FilterInstances(rsp), "polaris"))
+	assert.False(report.RevisionDrift)
+	assert.Equal(0, report.Problems())
+
+	report = Inspect(rsp, nil, nil)
+	assert.Empty(report.ServiceEntry)
+	assert.True(report.RevisionDrift)
+	assert.Equal(5, report.Problems())
+}
@@ -0,0 +1,231 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/polarismesh/polaris-go/pkg/model"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/protocol"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RegistryMethodServiceEntry syncs the polaris instances into the matched ServiceEntries
+	RegistryMethodServiceEntry = uint(1)
+	// RegistryMethodKubernetesService projects the polaris services into selector-less Services and EndpointSlices
+	RegistryMethodKubernetesService = uint(2)
+
+	// EndpointSliceManager is the value of the endpointslice.kubernetes.io/managed-by label,
+	// so that the EndpointSlices are left alone by the kubernetes EndpointSlice controller
+	EndpointSliceManager = "polaris2istio.aeraki.net"
+
+	maxEndpointsPerSlice = 100
+)
+
+// KubernetesServiceName covert the polaris service to a kubernetes service name
+func KubernetesServiceName(namespace string, name string) string {
+	return truncateName(strings.ReplaceAll(CovertServiceName(namespace, name), ".", "-"))
+}
+
+// ConvertKubernetesService covert the polaris service to a selector-less kubernetes Service and its EndpointSlices
+func ConvertKubernetesService(rsp *model.InstancesResponse, namespace string) (*corev1.Service,
+	[]*discoveryv1.EndpointSlice) {
+	name := KubernetesServiceName(rsp.GetNamespace(), rsp.GetService())
+	labels := convertProjectionLabels(rsp)
+
+	svc := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: convertPolarisAnnotations(rsp),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeClusterIP,
+			Ports: convertServicePorts(rsp),
+		},
+	}
+
+	sliceLabels := map[string]string{discoveryv1.LabelServiceName: name}
+	return svc, convertEndpointSlices(rsp, namespace, name, withLabels(sliceLabels, labels))
+}
+
+// convertProjectionLabels returns the labels of the objects which the polaris service is projected into
+func convertProjectionLabels(rsp *model.InstancesResponse) map[string]string {
+	return map[string]string{
+		ManagerLabel:        AerakiFieldManager,
+		RegistryLabel:       PolarisRegistry,
+		PolarisServiceLabel: WorkloadSelectorLabelValue(rsp.GetNamespace(), rsp.GetService()),
+	}
+}
+
+// withLabels adds the labels of the projected object and the managed-by label to the labels of the EndpointSlices
+func withLabels(sliceLabels map[string]string, labels map[string]string) map[string]string {
+	sliceLabels[discoveryv1.LabelManagedBy] = EndpointSliceManager
+	for k, v := range labels {
+		sliceLabels[k] = v
+	}
+	return sliceLabels
+}
+
+func convertPolarisAnnotations(rsp *model.InstancesResponse) map[string]string {
+	return map[string]string{
+		"aeraki.net/polarisNamespace": rsp.GetNamespace(),
+		"aeraki.net/polarisService":   rsp.GetService(),
+		"aeraki.net/revision":         rsp.GetRevision(),
+	}
+}
+
+func convertServicePorts(rsp *model.InstancesResponse) []corev1.ServicePort {
+	ports := make(map[uint32]corev1.ServicePort)
+	for _, instance := range rsp.Instances {
+		port := convertPort(int(instance.GetPort()), instance.GetProtocol())
+		if _, exists := ports[port.Number]; exists {
+			continue
+		}
+		ports[port.Number] = convertServicePort(port)
+	}
+
+	svcPorts := make([]corev1.ServicePort, 0, len(ports))
+	for _, port := range ports {
+		svcPorts = append(svcPorts, port)
+	}
+	sort.Slice(svcPorts, func(i, j int) bool {
+		return svcPorts[i].Port < svcPorts[j].Port
+	})
+	return svcPorts
+}
+
+// convertServicePort converts the port of the ServiceEntry, only UDP is not carried over TCP
+func convertServicePort(port *istio.Port) corev1.ServicePort {
+	appProtocol := strings.ToLower(port.Protocol)
+	transport := corev1.ProtocolTCP
+	if protocol.Instance(port.Protocol) == protocol.UDP {
+		transport = corev1.ProtocolUDP
+	}
+	return corev1.ServicePort{
+		Name:        fmt.Sprintf("%s-%d", appProtocol, port.Number),
+		Protocol:    transport,
+		AppProtocol: &appProtocol,
+		Port:        int32(port.Number),
+	}
+}
+
+type endpointSliceKey struct {
+	addressType discoveryv1.AddressType
+	port        uint32
+}
+
+// convertEndpointSlices groups the instances by address type and port, since all the endpoints
+// of an EndpointSlice share the same ports. The instance weights can not be carried by EndpointSlices.
+func convertEndpointSlices(rsp *model.InstancesResponse, namespace string, serviceName string,
+	labels map[string]string) []*discoveryv1.EndpointSlice {
+	groups := make(map[endpointSliceKey][]discoveryv1.Endpoint)
+	// the port names must match the ones of the service, the first protocol seen on a port wins
+	ports := make(map[uint32]corev1.ServicePort)
+
+	for _, instance := range rsp.Instances {
+		port := convertPort(int(instance.GetPort()), instance.GetProtocol())
+		key := endpointSliceKey{addressType: addressType(instance.GetHost()), port: port.Number}
+		groups[key] = append(groups[key], convertEndpoint(instance))
+		if _, exists := ports[port.Number]; !exists {
+			ports[port.Number] = convertServicePort(port)
+		}
+	}
+
+	keys := make([]endpointSliceKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].addressType != keys[j].addressType {
+			return keys[i].addressType < keys[j].addressType
+		}
+		return keys[i].port < keys[j].port
+	})
+
+	slices := make([]*discoveryv1.EndpointSlice, 0, len(keys))
+	for _, key := range keys {
+		endpoints := groups[key]
+		sort.Slice(endpoints, func(i, j int) bool {
+			return endpoints[i].Addresses[0] < endpoints[j].Addresses[0]
+		})
+		svcPort := ports[key.port]
+		for i := 0; i*maxEndpointsPerSlice < len(endpoints); i++ {
+			end := (i + 1) * maxEndpointsPerSlice
+			if end > len(endpoints) {
+				end = len(endpoints)
+			}
+			sliceLabels := make(map[string]string, len(labels))
+			for k, v := range labels {
+				sliceLabels[k] = v
+			}
+			slices = append(slices, &discoveryv1.EndpointSlice{
+				ObjectMeta: v1.ObjectMeta{
+					Name: truncateName(fmt.Sprintf("%s-%s-%d-%d", serviceName,
+						strings.ToLower(string(key.addressType)), key.port, i)),
+					Namespace: namespace,
+					Labels:    sliceLabels,
+				},
+				AddressType: key.addressType,
+				Endpoints:   endpoints[i*maxEndpointsPerSlice : end],
+				Ports: []discoveryv1.EndpointPort{{
+					Name:        &svcPort.Name,
+					Protocol:    &svcPort.Protocol,
+					Port:        &svcPort.Port,
+					AppProtocol: svcPort.AppProtocol,
+				}},
+			})
+		}
+	}
+	return slices
+}
+
+func convertEndpoint(instance model.Instance) discoveryv1.Endpoint {
+	healthy := instance.IsHealthy()
+	ready := healthy && !instance.IsIsolated()
+	terminating := false
+	endpoint := discoveryv1.Endpoint{
+		Addresses: []string{instance.GetHost()},
+		Conditions: discoveryv1.EndpointConditions{
+			Ready:       &ready,
+			Serving:     &healthy,
+			Terminating: &terminating,
+		},
+	}
+	if zone := instance.GetZone(); zone != "" {
+		endpoint.Zone = &zone
+	}
+	return endpoint
+}
+
+func addressType(host string) discoveryv1.AddressType {
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return discoveryv1.AddressTypeFQDN
+	case ip.To4() != nil:
+		return discoveryv1.AddressTypeIPv4
+	default:
+		return discoveryv1.AddressTypeIPv6
+	}
+}
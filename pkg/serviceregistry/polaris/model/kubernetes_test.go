@@ -0,0 +1,75 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/polarismesh/polaris-go/pkg/model/pb"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+func TestConvertKubernetesService(t *testing.T) {
+	assert := assert.New(t)
+	rsp := newTestInstancesResponse("Test", "rating_v1",
+		newTestInstance("Test", "rating_v1", "10.0.0.2", 8080, false, nil),
+		newTestInstance("Test", "rating_v1", "10.0.0.1", 8080, true, nil),
+		newTestInstance("Test", "rating_v1", "rating.example.com", 8080, true, nil),
+	)
+
+	svc, slices := ConvertKubernetesService(rsp, "polaris")
+	assert.Equal("test-polaris-rating-v1", svc.Name)
+	assert.Equal("polaris", svc.Namespace)
+	assert.Empty(svc.Spec.Selector)
+	assert.Len(svc.Spec.Ports, 1)
+	assert.Equal("http-8080", svc.Spec.Ports[0].Name)
+	assert.Equal("http", *svc.Spec.Ports[0].AppProtocol)
+	assert.Equal("rev-1", svc.Annotations["aeraki.net/revision"])
+
+	assert.Len(slices, 2)
+	ipv4 := slices[1]
+	assert.Equal(discoveryv1.AddressTypeIPv4, ipv4.AddressType)
+	assert.Equal("test-polaris-rating-v1", ipv4.Labels[discoveryv1.LabelServiceName])
+	assert.Equal(EndpointSliceManager, ipv4.Labels[discoveryv1.LabelManagedBy])
+	assert.Equal("http-8080", *ipv4.Ports[0].Name)
+	assert.Len(ipv4.Endpoints, 2)
+	assert.Equal([]string{"10.0.0.1"}, ipv4.Endpoints[0].Addresses)
+	assert.True(*ipv4.Endpoints[0].Conditions.Ready)
+	assert.False(*ipv4.Endpoints[1].Conditions.Ready)
+	assert.Equal(discoveryv1.AddressTypeFQDN, slices[0].AddressType)
+}
+
+func TestConvertServicePorts(t *testing.T) {
+	assert := assert.New(t)
+	dns := newTestInstance("Test", "dns", "10.0.0.1", 53, true, nil).(*pb.InstanceInProto)
+	dns.Protocol = &wrappers.StringValue{Value: "udp"}
+	grpc := newTestInstance("Test", "dns", "10.0.0.1", 9090, true, nil).(*pb.InstanceInProto)
+	grpc.Protocol = &wrappers.StringValue{Value: "grpc"}
+
+	svc, slices := ConvertKubernetesService(newTestInstancesResponse("Test", "dns", dns, grpc), "polaris")
+	assert.Len(svc.Spec.Ports, 2)
+	assert.Equal("udp-53", svc.Spec.Ports[0].Name)
+	assert.Equal(corev1.ProtocolUDP, svc.Spec.Ports[0].Protocol)
+	assert.Equal(corev1.ProtocolTCP, svc.Spec.Ports[1].Protocol)
+	assert.Equal(corev1.ProtocolUDP, *slices[0].Ports[0].Protocol)
+
+	// the service without instances has no ports, it is not written
+	svc, slices = ConvertKubernetesService(newTestInstancesResponse("Test", "dns"), "polaris")
+	assert.Empty(svc.Spec.Ports)
+	assert.Empty(slices)
+}
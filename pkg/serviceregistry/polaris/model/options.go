@@ -0,0 +1,138 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/polarismesh/polaris-go/pkg/model"
+)
+
+const (
+	// DefaultHostnameTemplate is the default template of the hostnames of the polaris services
+	DefaultHostnameTemplate = "{{.Namespace}}.polaris-{{.Service}}.polaris"
+
+	// HealthPolicyAll keeps all the polaris instances, the health is left to the outlier detection of the mesh
+	HealthPolicyAll = "all"
+	// HealthPolicyHealthy only keeps the healthy and not isolated polaris instances
+	HealthPolicyHealthy = "healthy"
+)
+
+// ConversionOptions control how the polaris instances are converted to the istio configs
+type ConversionOptions struct {
+	// HostnameTemplate is the go template of the hostnames of the polaris services,
+	// with the Namespace and Service fields
+	HostnameTemplate string
+	// HealthPolicy decides which polaris instances are kept according to their health
+	HealthPolicy string
+	// InstanceFilter only keeps the polaris instances with all the metadata
+	InstanceFilter map[string]string
+
+	hostnameTemplate *template.Template
+}
+
+// hostnameData is the data of the hostname template
+type hostnameData struct {
+	Namespace string
+	Service   string
+}
+
+// Validate checks the options and compiles the hostname template
+func (o *ConversionOptions) Validate() error {
+	if o.HostnameTemplate == "" {
+		o.HostnameTemplate = DefaultHostnameTemplate
+	}
+	tmpl, err := template.New("hostname").Option("missingkey=error").Parse(o.HostnameTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid hostname template: %v", err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, hostnameData{Namespace: "ns", Service: "svc"}); err != nil {
+		return fmt.Errorf("invalid hostname template: %v", err)
+	}
+	o.hostnameTemplate = tmpl
+
+	switch o.HealthPolicy {
+	case "":
+		o.HealthPolicy = HealthPolicyAll
+	case HealthPolicyAll, HealthPolicyHealthy:
+	default:
+		return fmt.Errorf("unknown health policy: %v", o.HealthPolicy)
+	}
+	return nil
+}
+
+func (o *ConversionOptions) hostname(namespace string, name string) (string, error) {
+	var buf bytes.Buffer
+	if err := o.hostnameTemplate.Execute(&buf, hostnameData{Namespace: namespace, Service: name}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// rejectReason returns why the instance is excluded by the health policy or the instance filter, it is empty if the
+// instance is kept
+func (o *ConversionOptions) rejectReason(instance model.Instance) string {
+	if o.HealthPolicy == HealthPolicyHealthy {
+		if !instance.IsHealthy() {
+			return "unhealthy"
+		}
+		if instance.IsIsolated() {
+			return "isolated"
+		}
+	}
+	if len(o.InstanceFilter) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(o.InstanceFilter))
+	for k := range o.InstanceFilter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	metadata := instance.GetMetadata()
+	for _, k := range keys {
+		if value, exists := metadata[k]; !exists || value != o.InstanceFilter[k] {
+			return fmt.Sprintf("metadata %v is not %v", k, o.InstanceFilter[k])
+		}
+	}
+	return ""
+}
+
+var conversionOptions atomic.Value
+
+func init() {
+	opts := &ConversionOptions{}
+	if err := opts.Validate(); err != nil {
+		panic(err)
+	}
+	conversionOptions.Store(opts)
+}
+
+// SetConversionOptions validates the options and applies them to the following conversions
+func SetConversionOptions(opts *ConversionOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	conversionOptions.Store(opts)
+	return nil
+}
+
+// GetConversionOptions returns the options of the conversions
+func GetConversionOptions() *ConversionOptions {
+	return conversionOptions.Load().(*ConversionOptions)
+}
@@ -0,0 +1,53 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversionOptions(t *testing.T) {
+	assert := assert.New(t)
+	defer SetConversionOptions(&ConversionOptions{})
+
+	assert.Equal("test.polaris-rating.polaris", CovertServiceHostname("Test", "rating"))
+
+	assert.NotNil(SetConversionOptions(&ConversionOptions{HostnameTemplate: "{{.Namespace"}))
+	assert.NotNil(SetConversionOptions(&ConversionOptions{HostnameTemplate: "{{.Cluster}}"}))
+	assert.NotNil(SetConversionOptions(&ConversionOptions{HealthPolicy: "unknown"}))
+	// the invalid options are not applied
+	assert.Equal("test.polaris-rating.polaris", CovertServiceHostname("Test", "rating"))
+
+	assert.Nil(SetConversionOptions(&ConversionOptions{
+		HostnameTemplate: "{{.Service}}.{{.Namespace}}.polaris.local",
+		HealthPolicy:     HealthPolicyHealthy,
+		InstanceFilter:   map[string]string{"env": "prod"},
+	}))
+	assert.Equal("rating.test.polaris.local", CovertServiceHostname("Test", "rating"))
+
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, map[string]string{"env": "prod"}),
+		newTestInstance("Test", "rating", "10.0.0.2", 8080, false, map[string]string{"env": "prod"}),
+		newTestInstance("Test", "rating", "10.0.0.3", 8080, true, map[string]string{"env": "test"}),
+	)
+	filtered := FilterInstances(rsp)
+	assert.Len(filtered.Instances, 1)
+	assert.Equal("10.0.0.1", filtered.Instances[0].GetHost())
+	assert.Equal("", FilterReason(rsp.Instances[0]))
+	assert.Equal("unhealthy", FilterReason(rsp.Instances[1]))
+	assert.Equal("metadata env is not prod", FilterReason(rsp.Instances[2]))
+}
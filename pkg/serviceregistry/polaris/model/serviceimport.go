@@ -0,0 +1,68 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"github.com/polarismesh/polaris-go/pkg/model"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	mcs "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+)
+
+const (
+	// RegistryMethodServiceImport projects the polaris services into multi-cluster ServiceImports and EndpointSlices
+	RegistryMethodServiceImport = uint(3)
+
+	// SourceClusterLabel is the label of the cluster which the EndpointSlices of a ServiceImport come from
+	SourceClusterLabel = "multicluster.kubernetes.io/source-cluster"
+)
+
+// ConvertServiceImport covert the polaris service to a multi-cluster ServiceImport and its EndpointSlices,
+// the polaris registry is regarded as the source cluster of the endpoints
+func ConvertServiceImport(rsp *model.InstancesResponse, namespace string) (*mcs.ServiceImport,
+	[]*discoveryv1.EndpointSlice) {
+	name := KubernetesServiceName(rsp.GetNamespace(), rsp.GetService())
+	labels := convertProjectionLabels(rsp)
+
+	svcPorts := convertServicePorts(rsp)
+	ports := make([]mcs.ServicePort, 0, len(svcPorts))
+	for _, port := range svcPorts {
+		ports = append(ports, mcs.ServicePort{
+			Name:        port.Name,
+			Protocol:    port.Protocol,
+			AppProtocol: port.AppProtocol,
+			Port:        port.Port,
+		})
+	}
+
+	serviceImport := &mcs.ServiceImport{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: convertPolarisAnnotations(rsp),
+		},
+		Spec: mcs.ServiceImportSpec{
+			Type:  mcs.ClusterSetIP,
+			Ports: ports,
+		},
+	}
+
+	sliceLabels := map[string]string{
+		mcs.LabelServiceName: name,
+		SourceClusterLabel:   PolarisRegistry,
+	}
+	return serviceImport, convertEndpointSlices(rsp, namespace, name, withLabels(sliceLabels, labels))
+}
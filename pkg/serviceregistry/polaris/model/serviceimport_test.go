@@ -0,0 +1,46 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	mcs "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+)
+
+func TestConvertServiceImport(t *testing.T) {
+	assert := assert.New(t)
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil),
+		newTestInstance("Test", "rating", "10.0.0.2", 9090, true, nil),
+	)
+
+	serviceImport, slices := ConvertServiceImport(rsp, "polaris")
+	assert.Equal("test-polaris-rating", serviceImport.Name)
+	assert.Equal(mcs.ClusterSetIP, serviceImport.Spec.Type)
+	assert.Len(serviceImport.Spec.Ports, 2)
+	assert.Equal("http-8080", serviceImport.Spec.Ports[0].Name)
+	assert.Equal(int32(9090), serviceImport.Spec.Ports[1].Port)
+
+	assert.Len(slices, 2)
+	for _, slice := range slices {
+		assert.Equal("test-polaris-rating", slice.Labels[mcs.LabelServiceName])
+		assert.Equal(PolarisRegistry, slice.Labels[SourceClusterLabel])
+		assert.NotContains(slice.Labels, discoveryv1.LabelServiceName)
+		assert.Len(slice.Endpoints, 1)
+	}
+}
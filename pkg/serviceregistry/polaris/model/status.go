@@ -0,0 +1,82 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/polarismesh/polaris-go/pkg/model"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyncStatusAnnotation is the annotation of the source ServiceEntry which holds the sync status in JSON
+const SyncStatusAnnotation = "aeraki.net/syncStatus"
+
+// SyncStatus is the status of the sync of the polaris service
+type SyncStatus struct {
+	// LastSyncTime is when the polaris service was last synced, successfully or not
+	LastSyncTime v1.Time `json:"lastSyncTime"`
+	// LastSuccessTime is when the polaris service was last synced successfully
+	LastSuccessTime *v1.Time `json:"lastSuccessTime,omitempty"`
+	// Revision is the polaris revision last synced successfully
+	Revision  string         `json:"revision,omitempty"`
+	Endpoints EndpointCounts `json:"endpoints"`
+	LastError string         `json:"lastError,omitempty"`
+	// ObservedGeneration is the generation of the source ServiceEntry the status is written for
+	ObservedGeneration int64 `json:"observedGeneration"`
+}
+
+// EndpointCounts are the numbers of the instances of the polaris service when it was last synced
+type EndpointCounts struct {
+	Total int `json:"total"`
+	// Healthy is the number of the healthy instances which are not filtered out
+	Healthy int `json:"healthy"`
+	// Filtered is the number of the instances registered from the mesh or rejected by the conversion options
+	Filtered int `json:"filtered"`
+}
+
+// CountEndpoints counts the instances, total is the number of the instances before they are filtered
+func CountEndpoints(total int, filtered *model.InstancesResponse) EndpointCounts {
+	counts := EndpointCounts{Total: total, Filtered: total - len(filtered.GetInstances())}
+	for _, instance := range filtered.GetInstances() {
+		if instance.IsHealthy() {
+			counts.Healthy++
+		}
+	}
+	return counts
+}
+
+// GetSyncStatus returns the sync status in the annotations, it is nil if there is no sync status
+func GetSyncStatus(annotations map[string]string) (*SyncStatus, error) {
+	value, exists := annotations[SyncStatusAnnotation]
+	if !exists {
+		return nil, nil
+	}
+	status := &SyncStatus{}
+	if err := json.Unmarshal([]byte(value), status); err != nil {
+		return nil, fmt.Errorf("invalid %v annotation: %v", SyncStatusAnnotation, err)
+	}
+	return status, nil
+}
+
+// Annotation returns the sync status in JSON
+func (s *SyncStatus) Annotation() (string, error) {
+	value, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
@@ -0,0 +1,61 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCountEndpoints(t *testing.T) {
+	assert := assert.New(t)
+	rsp := newTestInstancesResponse("Production", "reviews",
+		newTestInstance("Production", "reviews", "10.0.0.1", 8080, true, nil),
+		newTestInstance("Production", "reviews", "10.0.0.2", 8080, false, nil))
+	assert.Equal(EndpointCounts{Total: 3, Healthy: 1, Filtered: 1}, CountEndpoints(3, rsp))
+}
+
+func TestSyncStatusAnnotation(t *testing.T) {
+	assert := assert.New(t)
+	status, err := GetSyncStatus(map[string]string{})
+	assert.Nil(err)
+	assert.Nil(status)
+
+	now := v1.NewTime(time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC))
+	status = &SyncStatus{
+		LastSyncTime:       now,
+		LastSuccessTime:    &now,
+		Revision:           "rev-1",
+		Endpoints:          EndpointCounts{Total: 3, Healthy: 2},
+		ObservedGeneration: 2,
+	}
+	value, err := status.Annotation()
+	assert.Nil(err)
+	assert.Equal(`{"lastSyncTime":"2022-06-01T08:00:00Z","lastSuccessTime":"2022-06-01T08:00:00Z",`+
+		`"revision":"rev-1","endpoints":{"total":3,"healthy":2,"filtered":0},"observedGeneration":2}`, value)
+
+	parsed, err := GetSyncStatus(map[string]string{SyncStatusAnnotation: value})
+	assert.Nil(err)
+	assert.True(now.Equal(parsed.LastSuccessTime))
+	assert.Equal("rev-1", parsed.Revision)
+	assert.Equal(status.Endpoints, parsed.Endpoints)
+	assert.Equal(int64(2), parsed.ObservedGeneration)
+
+	_, err = GetSyncStatus(map[string]string{SyncStatusAnnotation: "synced"})
+	assert.NotNil(err)
+}
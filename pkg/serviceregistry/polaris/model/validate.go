@@ -14,12 +14,187 @@
 
 package model
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	istiovalidation "istio.io/istio/pkg/config/validation"
+)
+
+// ErrInvalidConversion is returned when the config converted from the polaris instances is rejected by the istio
+// validation, the config would be discarded by istiod if it was written
+var ErrInvalidConversion = errors.New("invalid conversion")
+
+const annotationPrefix = "aeraki.net/"
+
+// knownAnnotations are the annotations of the source ServiceEntries read or written by polaris2istio
+var knownAnnotations = []string{
+	"aeraki.net/polarisNamespace",
+	"aeraki.net/polarisService",
+	"aeraki.net/external",
+	"aeraki.net/workloadEntry",
+	"aeraki.net/revision",
+	SyncStatusAnnotation,
+}
+
+// IsPolarisServiceEntry returns whether the ServiceEntry is meant to be synced from the polaris, it has the
+// registry label or any of the polaris annotations
+func IsPolarisServiceEntry(se *v1alpha3.ServiceEntry) bool {
+	if se.GetLabels()[RegistryLabel] == PolarisRegistry {
+		return true
+	}
+	for key := range se.GetAnnotations() {
+		if strings.HasPrefix(strings.ToLower(key), annotationPrefix+"polaris") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePolarisServiceEntry checks the labels and the polaris annotations of the source ServiceEntry, all the
+// problems are reported at once
+func ValidatePolarisServiceEntry(se *v1alpha3.ServiceEntry) error {
+	var errs []string
+	for _, label := range []struct{ key, value string }{
+		{ManagerLabel, AerakiFieldManager},
+		{RegistryLabel, PolarisRegistry},
+	} {
+		value, exists := se.GetLabels()[label.key]
+		if !exists {
+			errs = append(errs, fmt.Sprintf("label %v is required to be matched, it should be %q",
+				label.key, label.value))
+		} else if value != label.value {
+			errs = append(errs, fmt.Sprintf("label %v should be %q, got %q", label.key, label.value, value))
+		}
+	}
+	if err := validatePolarisSEAnnotations(se.GetAnnotations()); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
 
 func validatePolarisSEAnnotations(annotations map[string]string) error {
-	if len(annotations) < 2 {
-		return fmt.Errorf("polaris service entry annotations must be at least 2 ")
+	var errs []string
+	for _, key := range []string{"aeraki.net/polarisNamespace", "aeraki.net/polarisService"} {
+		value, exists := annotations[key]
+		if !exists {
+			errs = append(errs, fmt.Sprintf("annotation %v is required", key))
+		} else if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Sprintf("annotation %v should not be empty", key))
+		}
+	}
+	for _, key := range []string{"aeraki.net/external", "aeraki.net/workloadEntry"} {
+		if value, exists := annotations[key]; exists && value != "true" && value != "false" {
+			errs = append(errs, fmt.Sprintf("annotation %v should be \"true\" or \"false\", got %q", key, value))
+		}
+	}
+
+	// the annotations which look like a known one are typos, the other aeraki.net annotations are left alone
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if suggestion := suggestAnnotation(key); suggestion != "" {
+			errs = append(errs, fmt.Sprintf("unknown annotation %v, did you mean %v?", key, suggestion))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
+	return nil
+}
+
+// suggestAnnotation returns the known annotation the key is probably a typo of, it is empty if the key is known
+// or it is not close to any known annotation
+func suggestAnnotation(key string) string {
+	if !strings.HasPrefix(strings.ToLower(key), annotationPrefix) {
+		return ""
+	}
+	for _, known := range knownAnnotations {
+		if key == known {
+			return ""
+		}
+	}
+	name := strings.ToLower(key[len(annotationPrefix):])
+	for _, known := range knownAnnotations {
+		knownName := strings.ToLower(known[len(annotationPrefix):])
+		if editDistance(name, knownName) <= 2 {
+			return known
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance of the strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
 
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// ValidateServiceEntry checks the converted ServiceEntry with the validation of istio
+func ValidateServiceEntry(name string, namespace string, spec *istio.ServiceEntry) error {
+	return validateConfig(istiovalidation.ValidateServiceEntry, config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.ServiceEntry, Name: name, Namespace: namespace},
+		Spec: spec,
+	})
+}
+
+// ValidateWorkloadEntries checks the converted WorkloadEntries with the validation of istio, the first invalid one
+// is reported
+func ValidateWorkloadEntries(entries []*v1alpha3.WorkloadEntry) error {
+	for _, entry := range entries {
+		if err := validateConfig(istiovalidation.ValidateWorkloadEntry, config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.WorkloadEntry, Name: entry.Name, Namespace: entry.Namespace},
+			Spec: &entry.Spec,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateConfig runs the istio validation of the config, the warnings are not errors
+func validateConfig(validate istiovalidation.ValidateFunc, cfg config.Config) error {
+	if _, err := validate(cfg); err != nil {
+		return fmt.Errorf("%w: %v %v/%v is rejected by istio: %v", ErrInvalidConversion, cfg.GroupVersionKind.Kind,
+			cfg.Namespace, cfg.Name, err)
+	}
 	return nil
 }
@@ -15,10 +15,14 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestValidatePolarisSEAnnotations(t *testing.T) {
@@ -34,9 +38,91 @@ func TestValidatePolarisSEAnnotations(t *testing.T) {
 		}, nil},
 		{map[string]string{
 			"aeraki.net/polarisNamespace": "test",
-		}, fmt.Errorf("polaris service entry annotations must be at least 2 ")},
+		}, fmt.Errorf("annotation aeraki.net/polarisService is required")},
+		{map[string]string{
+			"aeraki.net/polarisNamespace": "test",
+			"aeraki.net/polarisService":   " ",
+			"aeraki.net/external":         "yes",
+		}, fmt.Errorf("annotation aeraki.net/polarisService should not be empty; " +
+			"annotation aeraki.net/external should be \"true\" or \"false\", got \"yes\"")},
+		{map[string]string{
+			"aeraki.net/polarisNamespace": "test",
+			"aeraki.net/polarisServce":    "rating",
+			"aeraki.net/External":         "true",
+			"aeraki.net/business":         "bar",
+		}, fmt.Errorf("annotation aeraki.net/polarisService is required; " +
+			"unknown annotation aeraki.net/External, did you mean aeraki.net/external?; " +
+			"unknown annotation aeraki.net/polarisServce, did you mean aeraki.net/polarisService?")},
 	}
 	for _, test := range tests {
 		assert.Equal(validatePolarisSEAnnotations(test.input), test.expected)
 	}
 }
+
+func TestValidatePolarisServiceEntry(t *testing.T) {
+	assert := assert.New(t)
+	se := &v1alpha3.ServiceEntry{ObjectMeta: v1.ObjectMeta{
+		Name: "reviews",
+		Annotations: map[string]string{
+			"aeraki.net/polarisNamespace": "test",
+			"aeraki.net/polarisService":   "reviews",
+		},
+	}}
+	assert.True(IsPolarisServiceEntry(se))
+	assert.Equal(fmt.Errorf("label manager is required to be matched, it should be \"aeraki\"; "+
+		"label registry is required to be matched, it should be \"polaris\""), ValidatePolarisServiceEntry(se))
+
+	se.Labels = map[string]string{ManagerLabel: "istio", RegistryLabel: PolarisRegistry}
+	assert.Equal(fmt.Errorf("label manager should be \"aeraki\", got \"istio\""), ValidatePolarisServiceEntry(se))
+
+	se.Labels[ManagerLabel] = AerakiFieldManager
+	assert.Nil(ValidatePolarisServiceEntry(se))
+
+	assert.False(IsPolarisServiceEntry(&v1alpha3.ServiceEntry{ObjectMeta: v1.ObjectMeta{
+		Annotations: map[string]string{"aeraki.net/business": "bar"},
+	}}))
+}
+
+func TestValidateServiceEntry(t *testing.T) {
+	assert := assert.New(t)
+	polarisInfo := &PolarisInfo{PolarisNamespace: "Test", PolarisService: "rating", External: "true"}
+
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil))
+	se, _ := ConvertServiceEntry(rsp, polarisInfo)
+	assert.Nil(ValidateServiceEntry("test.polaris-rating", "istio-system", se))
+
+	// the ports of the instances on the different ports with the same protocol are named by their numbers
+	rsp = newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil),
+		newTestInstance("Test", "rating", "10.0.0.2", 9090, true, nil))
+	se, _ = ConvertServiceEntry(rsp, polarisInfo)
+	assert.Equal([]string{"http-8080", "http-9090"}, []string{se.Ports[0].Name, se.Ports[1].Name})
+	assert.Equal(map[string]uint32{"http-9090": 9090}, se.Endpoints[1].Ports)
+	assert.Nil(ValidateServiceEntry("test.polaris-rating", "istio-system", se))
+
+	// the endpoints of a STATIC ServiceEntry must be IP addresses
+	rsp = newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "rating.example.com", 8080, true, nil))
+	se, _ = ConvertServiceEntry(rsp, polarisInfo)
+	assert.True(errors.Is(ValidateServiceEntry("test.polaris-rating", "istio-system", se), ErrInvalidConversion))
+
+	se = &istio.ServiceEntry{Ports: []*istio.Port{{Number: 8080, Protocol: "HTTP", Name: "http"}}}
+	err := ValidateServiceEntry("test.polaris-rating", "istio-system", se)
+	assert.True(errors.Is(err, ErrInvalidConversion))
+	assert.Contains(err.Error(), "ServiceEntry istio-system/test.polaris-rating is rejected by istio")
+}
+
+func TestValidateWorkloadEntries(t *testing.T) {
+	assert := assert.New(t)
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil))
+	assert.Nil(ValidateWorkloadEntries(ConvertWorkloadEntries(rsp, "istio-system")))
+
+	rsp = newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil),
+		newTestInstance("Test", "rating", "10.0.0.2", 0, true, nil))
+	err := ValidateWorkloadEntries(ConvertWorkloadEntries(rsp, "istio-system"))
+	assert.True(errors.Is(err, ErrInvalidConversion))
+	assert.Contains(err.Error(), "WorkloadEntry istio-system/")
+}
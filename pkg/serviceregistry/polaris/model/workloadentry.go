@@ -0,0 +1,142 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/polarismesh/polaris-go/pkg/model"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/pkg/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// PolarisServiceLabel is the label which binds the generated WorkloadEntries to their ServiceEntry
+	PolarisServiceLabel = "aeraki.net/polarisServiceEntry"
+	// ManagerLabel marks the objects managed by polaris2istio
+	ManagerLabel = "manager"
+	// RegistryLabel marks the registry of the objects managed by polaris2istio
+	RegistryLabel = "registry"
+	// AerakiFieldManager is the FileldManager for Aeraki CRDs
+	AerakiFieldManager = "aeraki"
+	// PolarisRegistry is the value of the registry label
+	PolarisRegistry = "polaris"
+
+	// maxNameLength is the max length of the kubernetes names and label values
+	maxNameLength = 63
+)
+
+// IsWorkloadEntryMode returns whether the polaris service should be projected into standalone WorkloadEntries
+func (p *PolarisInfo) IsWorkloadEntryMode() bool {
+	return p.WorkloadEntry == "true"
+}
+
+// WorkloadSelectorLabelValue returns the value of the PolarisServiceLabel for the given polaris service
+func WorkloadSelectorLabelValue(namespace string, name string) string {
+	return truncateName(CovertServiceName(namespace, name))
+}
+
+// ConvertWorkloadEntries covert the polaris instances to standalone WorkloadEntries,
+// one WorkloadEntry for each instance, labeled by the service
+func ConvertWorkloadEntries(rsp *model.InstancesResponse, namespace string) []*v1alpha3.WorkloadEntry {
+	seName := CovertServiceName(rsp.GetNamespace(), rsp.GetService())
+	selector := WorkloadSelectorLabelValue(rsp.GetNamespace(), rsp.GetService())
+	entries := make([]*v1alpha3.WorkloadEntry, 0, len(rsp.Instances))
+	names := make(map[string]struct{}, len(rsp.Instances))
+	_, portNames := convertPorts(rsp)
+
+	for _, instance := range rsp.Instances {
+		name := workloadEntryName(seName, instance)
+		if _, exists := names[name]; exists {
+			log.Warnf("Service %v has duplicated instance %v:%v, ignored",
+				rsp.GetService(), instance.GetHost(), instance.GetPort())
+			continue
+		}
+		names[name] = struct{}{}
+
+		labels := convertInstanceLabels(instance.GetMetadata())
+		labels[PolarisServiceLabel] = selector
+		spec := convertWorkloadEntry(instance, portNames)
+		spec.Labels = labels
+
+		objectLabels := make(map[string]string, len(labels)+2)
+		for k, v := range labels {
+			objectLabels[k] = v
+		}
+		objectLabels[ManagerLabel] = AerakiFieldManager
+		objectLabels[RegistryLabel] = PolarisRegistry
+
+		entry := &v1alpha3.WorkloadEntry{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    objectLabels,
+				Annotations: map[string]string{
+					"aeraki.net/polarisNamespace": rsp.GetNamespace(),
+					"aeraki.net/polarisService":   rsp.GetService(),
+					"aeraki.net/instanceId":       instance.GetId(),
+					"aeraki.net/revision":         instance.GetRevision(),
+					"aeraki.net/healthy":          strconv.FormatBool(instance.IsHealthy()),
+					"aeraki.net/isolated":         strconv.FormatBool(instance.IsIsolated()),
+				},
+			},
+		}
+		spec.DeepCopyInto(&entry.Spec)
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func workloadEntryName(seName string, instance model.Instance) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d", instance.GetHost(), instance.GetPort())))
+	return fmt.Sprintf("%s-%s", seName, hex.EncodeToString(sum[:])[:10])
+}
+
+// convertInstanceLabels keeps the instance metadata which are valid kubernetes labels
+func convertInstanceLabels(metadata map[string]string) map[string]string {
+	labels := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if len(validation.IsQualifiedName(k)) != 0 || len(validation.IsValidLabelValue(v)) != 0 {
+			log.Debugf("[convertInstanceLabels] skip the metadata which is not a valid label: %v=%v", k, v)
+			continue
+		}
+		labels[k] = v
+	}
+	return labels
+}
+
+// truncateName shortens the names exceeding the max length, a hash suffix is kept to avoid conflicts
+func truncateName(s string) string {
+	if len(s) <= maxNameLength {
+		return s
+	}
+	sum := sha1.Sum([]byte(s))
+	suffix := hex.EncodeToString(sum[:])[:10]
+	return strings.TrimRight(s[:maxNameLength-len(suffix)-1], "-.") + "-" + suffix
+}
+
+func convertWorkloadSelector(namespace string, name string) *istio.WorkloadSelector {
+	return &istio.WorkloadSelector{
+		Labels: map[string]string{PolarisServiceLabel: WorkloadSelectorLabelValue(namespace, name)},
+	}
+}
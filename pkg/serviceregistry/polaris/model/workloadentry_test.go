@@ -0,0 +1,99 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/polarismesh/polaris-go/pkg/model"
+	"github.com/polarismesh/polaris-go/pkg/model/local"
+	"github.com/polarismesh/polaris-go/pkg/model/pb"
+	namingpb "github.com/polarismesh/polaris-go/pkg/model/pb/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestInstance(namespace, service, host string, port uint32, healthy bool,
+	metadata map[string]string) model.Instance {
+	return pb.NewInstanceInProto(&namingpb.Instance{
+		Id:       &wrappers.StringValue{Value: host},
+		Host:     &wrappers.StringValue{Value: host},
+		Port:     &wrappers.UInt32Value{Value: port},
+		Protocol: &wrappers.StringValue{Value: "http"},
+		Weight:   &wrappers.UInt32Value{Value: 100},
+		Healthy:  &wrappers.BoolValue{Value: healthy},
+		Metadata: metadata,
+	}, &model.ServiceKey{Namespace: namespace, Service: service}, local.NewInstanceLocalValue())
+}
+
+func newTestInstancesResponse(namespace, service string, instances ...model.Instance) *model.InstancesResponse {
+	return &model.InstancesResponse{
+		ServiceInfo: model.ServiceInfo{Namespace: namespace, Service: service},
+		Revision:    "rev-1",
+		Instances:   instances,
+	}
+}
+
+func TestConvertWorkloadEntries(t *testing.T) {
+	assert := assert.New(t)
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, map[string]string{"version": "v1", "bad key": "x"}),
+		newTestInstance("Test", "rating", "10.0.0.2", 8080, false, nil),
+		newTestInstance("Test", "rating", "10.0.0.2", 8080, false, nil),
+	)
+
+	entries := ConvertWorkloadEntries(rsp, "polaris")
+	assert.Len(entries, 2)
+
+	first := entries[0]
+	assert.Equal("polaris", first.Namespace)
+	assert.Equal("10.0.0.1", first.Spec.Address)
+	assert.Equal(map[string]uint32{"http": 8080}, first.Spec.Ports)
+	assert.Equal(map[string]string{"version": "v1", PolarisServiceLabel: "test.polaris-rating"}, first.Spec.Labels)
+	assert.Equal(AerakiFieldManager, first.Labels[ManagerLabel])
+	assert.Equal("test.polaris-rating", first.Labels[PolarisServiceLabel])
+	assert.Equal("true", first.Annotations["aeraki.net/healthy"])
+	assert.Equal("false", entries[1].Annotations["aeraki.net/healthy"])
+	assert.NotEqual(first.Name, entries[1].Name)
+
+	// the ports are named as the ones of the ServiceEntry selecting the WorkloadEntries
+	rsp.Instances = append(rsp.Instances, newTestInstance("Test", "rating", "10.0.0.3", 9090, true, nil))
+	entries = ConvertWorkloadEntries(rsp, "polaris")
+	assert.Equal(map[string]uint32{"http-8080": 8080}, entries[0].Spec.Ports)
+	assert.Equal(map[string]uint32{"http-9090": 9090}, entries[2].Spec.Ports)
+}
+
+func TestConvertServiceEntryWithWorkloadSelector(t *testing.T) {
+	assert := assert.New(t)
+	rsp := newTestInstancesResponse("Test", "rating",
+		newTestInstance("Test", "rating", "10.0.0.1", 8080, true, nil))
+
+	se, annotations := ConvertServiceEntry(rsp, &PolarisInfo{External: "true", WorkloadEntry: "true"})
+	assert.Empty(se.Endpoints)
+	assert.Equal(map[string]string{PolarisServiceLabel: "test.polaris-rating"}, se.WorkloadSelector.GetLabels())
+	assert.Equal("true", annotations["aeraki.net/workloadEntry"])
+
+	se, annotations = ConvertServiceEntry(rsp, &PolarisInfo{External: "true"})
+	assert.Len(se.Endpoints, 1)
+	assert.Nil(se.WorkloadSelector)
+	assert.NotContains(annotations, "aeraki.net/workloadEntry")
+}
+
+func TestTruncateName(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("short", truncateName("short"))
+	long := truncateName("a-very-long-polaris-namespace.polaris-a-very-long-polaris-service-name")
+	assert.Len(long, maxNameLength)
+}
@@ -0,0 +1,241 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	"istio.io/pkg/log"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	defaultResyncPeriod = 30 * time.Second
+)
+
+// Options are the options of the register controller
+type Options struct {
+	// LabelSelector selects the kubernetes services to register into the polaris
+	LabelSelector string
+	// DefaultNamespace is the polaris namespace of the services not in the NamespaceMapping,
+	// the kubernetes namespace is used when it's empty
+	DefaultNamespace string
+	// NamespaceMapping maps the kubernetes namespaces to the polaris namespaces
+	NamespaceMapping map[string]string
+	// HeartbeatInterval is the interval of the heartbeats of the registered instances
+	HeartbeatInterval time.Duration
+}
+
+// Controller registers the kubernetes services selected by the label selector into the polaris
+type Controller struct {
+	registry *registry
+
+	mutex sync.RWMutex
+	opts  Options
+	// changed is signaled when the namespace mapping is changed, the Run loop of the leader registers the services again
+	changed chan struct{}
+
+	svcInformers  informers.SharedInformerFactory
+	epsInformers  informers.SharedInformerFactory
+	serviceLister corelisters.ServiceLister
+	sliceLister   discoverylisters.EndpointSliceLister
+	queue         workqueue.RateLimitingInterface
+}
+
+// NewController creates a register controller watching the kubernetes services with the client
+func NewController(kc kubernetes.Interface, provider *polaris.PolarisProviderClient, opts Options) (*Controller,
+	error) {
+	if _, err := labels.Parse(opts.LabelSelector); err != nil {
+		return nil, fmt.Errorf("invalid label selector %v: %v", opts.LabelSelector, err)
+	}
+	if opts.HeartbeatInterval <= 0 {
+		opts.HeartbeatInterval = HeartbeatTTL * time.Second / 2
+	}
+
+	c := &Controller{
+		registry: newRegistry(provider),
+		opts:     opts,
+		changed:  make(chan struct{}, 1),
+		svcInformers: informers.NewSharedInformerFactoryWithOptions(kc, defaultResyncPeriod,
+			informers.WithTweakListOptions(func(options *v1.ListOptions) {
+				options.LabelSelector = opts.LabelSelector
+			})),
+		// the EndpointSlices generated by polaris2istio are never watched
+		epsInformers: informers.NewSharedInformerFactoryWithOptions(kc, defaultResyncPeriod,
+			informers.WithTweakListOptions(func(options *v1.ListOptions) {
+				options.LabelSelector = discoveryv1.LabelManagedBy + "!=" + model.EndpointSliceManager
+			})),
+		queue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+
+	serviceInformer := c.svcInformers.Core().V1().Services()
+	c.serviceLister = serviceInformer.Lister()
+	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		DeleteFunc: c.enqueue,
+	})
+
+	sliceInformer := c.epsInformers.Discovery().V1().EndpointSlices()
+	c.sliceLister = sliceInformer.Lister()
+	sliceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueSlice,
+		UpdateFunc: func(_, obj interface{}) { c.enqueueSlice(obj) },
+		DeleteFunc: c.enqueueSlice,
+	})
+
+	return c, nil
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Errorf("[register] failed to get the key of %v: %v", obj, err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *Controller) enqueueSlice(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	slice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		return
+	}
+	if name, exists := slice.Labels[discoveryv1.LabelServiceName]; exists {
+		c.queue.Add(slice.Namespace + "/" + name)
+	}
+}
+
+// Run starts the controller, all the registered instances are deregistered when it is stopped
+func (c *Controller) Run(stop <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	c.svcInformers.Start(stop)
+	c.epsInformers.Start(stop)
+	c.svcInformers.WaitForCacheSync(stop)
+	c.epsInformers.WaitForCacheSync(stop)
+	log.Infof("[register] start to register the kubernetes services selected by %v", c.opts.LabelSelector)
+
+	go c.runWorker()
+
+	ticker := time.NewTicker(c.opts.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.heartbeat()
+		case <-c.changed:
+			c.enqueueAll()
+		case <-stop:
+			log.Info("[register] stopping, deregister all the instances")
+			c.registry.deregisterAll()
+			return
+		}
+	}
+}
+
+func (c *Controller) runWorker() {
+	for {
+		item, shutdown := c.queue.Get()
+		if shutdown {
+			return
+		}
+		key := item.(string)
+		if err := c.reconcile(key); err != nil {
+			log.Errorf("[register] failed to register service %v: %v", key, err)
+			c.queue.AddRateLimited(key)
+		} else {
+			c.queue.Forget(key)
+		}
+		c.queue.Done(item)
+	}
+}
+
+// reconcile registers the new instances and deregisters the removed ones of the kubernetes service
+func (c *Controller) reconcile(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	var desired map[string]*polaris.PolarisInstance
+	svc, err := c.serviceLister.Services(namespace).Get(name)
+	switch {
+	case errors.IsNotFound(err):
+		desired = map[string]*polaris.PolarisInstance{}
+	case err != nil:
+		return err
+	default:
+		slices, err := c.sliceLister.EndpointSlices(namespace).List(labels.SelectorFromSet(labels.Set{
+			discoveryv1.LabelServiceName: name,
+		}))
+		if err != nil {
+			return err
+		}
+		c.mutex.RLock()
+		opts := c.opts
+		c.mutex.RUnlock()
+		desired = opts.desiredInstances(svc, slices)
+	}
+
+	return c.registry.sync(key, desired)
+}
+
+// SetNamespaceMapping changes the mapping to the polaris namespaces. It only stores the new mapping, the running
+// controller registers all the services again so that the instances are moved to their new polaris services.
+func (c *Controller) SetNamespaceMapping(defaultNamespace string, mapping map[string]string) {
+	c.mutex.Lock()
+	c.opts.DefaultNamespace = defaultNamespace
+	c.opts.NamespaceMapping = mapping
+	c.mutex.Unlock()
+
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Controller) enqueueAll() {
+	services, err := c.serviceLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("[register] failed to list the services: %v", err)
+		return
+	}
+	for _, svc := range services {
+		c.queue.Add(svc.Namespace + "/" + svc.Name)
+	}
+}
+
+func (c *Controller) heartbeat() {
+	for _, key := range c.registry.heartbeat(nil) {
+		c.queue.Add(key)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"testing"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestControllerInformers(t *testing.T) {
+	assert := assert.New(t)
+	kc := fake.NewSimpleClientset(
+		&corev1.Service{ObjectMeta: v1.ObjectMeta{Name: "reviews", Namespace: "default",
+			Labels: map[string]string{"aeraki.net/polarisRegister": "true"}}},
+		&corev1.Service{ObjectMeta: v1.ObjectMeta{Name: "ratings", Namespace: "default"}},
+		newTestSlice("reviews-1", "endpointslice-controller.k8s.io", true),
+		newTestSlice("reviews-2", model.EndpointSliceManager, true),
+	)
+	c, err := NewController(kc, nil, Options{LabelSelector: "aeraki.net/polarisRegister=true"})
+	assert.Nil(err)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c.svcInformers.Start(stop)
+	c.epsInformers.Start(stop)
+	c.svcInformers.WaitForCacheSync(stop)
+	c.epsInformers.WaitForCacheSync(stop)
+
+	// only the labeled services are watched, and the EndpointSlices generated by polaris2istio are ignored
+	services, err := c.serviceLister.List(labels.Everything())
+	assert.Nil(err)
+	assert.Len(services, 1)
+	slices, err := c.sliceLister.List(labels.Everything())
+	assert.Nil(err)
+	assert.Len(slices, 1)
+	assert.Equal("reviews-1", slices[0].Name)
+
+	// the changed mapping is only stored, the running controller registers the services again
+	for c.queue.Len() > 0 {
+		item, _ := c.queue.Get()
+		c.queue.Done(item)
+	}
+	c.SetNamespaceMapping("Production", map[string]string{"default": "Test"})
+	assert.Equal(0, c.queue.Len())
+	assert.Len(c.changed, 1)
+	c.enqueueAll()
+	assert.Equal(1, c.queue.Len())
+
+	_, err = NewController(kc, nil, Options{LabelSelector: "a b"})
+	assert.NotNil(err)
+}
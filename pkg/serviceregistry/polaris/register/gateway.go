@@ -0,0 +1,269 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	"istio.io/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MeshHostMetadata is the metadata key of the mesh host which a gateway instance is registered for
+	MeshHostMetadata = "aeraki.net/meshHost"
+)
+
+// PolarisServiceKey is the namespace and name of a polaris service
+type PolarisServiceKey struct {
+	Namespace string
+	Service   string
+}
+
+// GatewayOptions are the options of the gateway registrar
+type GatewayOptions struct {
+	// Gateway is the namespace/name of the kubernetes service of the gateway
+	Gateway string
+	// PortName is the name of the gateway service port which the polaris consumers connect to
+	PortName string
+	// Hosts maps the mesh hosts to the polaris services which the gateway addresses are registered into
+	Hosts map[string]PolarisServiceKey
+	// Interval is the interval of the heartbeats and the rediscovery of the gateway addresses
+	Interval time.Duration
+}
+
+// ParseGatewayHosts parses the gateway hosts in the format of <mesh-host>=<polaris-namespace>/<polaris-service>,...
+func ParseGatewayHosts(s string) (map[string]PolarisServiceKey, error) {
+	hosts := make(map[string]PolarisServiceKey)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid gateway host: %v", item)
+		}
+		svc := strings.SplitN(kv[1], "/", 2)
+		if len(svc) != 2 || svc[0] == "" || svc[1] == "" {
+			return nil, fmt.Errorf("invalid polaris service of gateway host %v: %v", kv[0], kv[1])
+		}
+		hosts[kv[0]] = PolarisServiceKey{Namespace: svc[0], Service: svc[1]}
+	}
+	return hosts, nil
+}
+
+// GatewayRegistrar registers the addresses of the mesh gateway into the polaris, as the instances of the
+// polaris services of the selected mesh hosts
+type GatewayRegistrar struct {
+	kc        kubernetes.Interface
+	registry  *registry
+	namespace string
+	name      string
+	healthy   bool
+
+	mutex sync.RWMutex
+	opts  GatewayOptions
+	// changed is signaled when the hosts are changed, the Run loop applies them
+	changed chan struct{}
+}
+
+// NewGatewayRegistrar creates a gateway registrar discovering the gateway service with the client
+func NewGatewayRegistrar(kc kubernetes.Interface, provider *polaris.PolarisProviderClient,
+	opts GatewayOptions) (*GatewayRegistrar, error) {
+	parts := strings.SplitN(opts.Gateway, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid gateway service, it should be namespace/name: %v", opts.Gateway)
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = HeartbeatTTL * time.Second / 2
+	}
+
+	return &GatewayRegistrar{
+		kc:        kc,
+		registry:  newRegistry(provider),
+		opts:      opts,
+		changed:   make(chan struct{}, 1),
+		namespace: parts[0],
+		name:      parts[1],
+	}, nil
+}
+
+// Run starts the registrar, all the registered instances are deregistered when it is stopped
+func (g *GatewayRegistrar) Run(stop <-chan struct{}) {
+	g.mutex.RLock()
+	log.Infof("[register] start to register the gateway %v for the mesh hosts: %v", g.opts.Gateway, g.opts.Hosts)
+	g.mutex.RUnlock()
+	ticker := time.NewTicker(g.opts.Interval)
+	defer ticker.Stop()
+	g.sync()
+	for {
+		select {
+		case <-ticker.C:
+			g.sync()
+		case <-g.changed:
+			g.deregisterRemovedHosts()
+			g.sync()
+		case <-stop:
+			log.Info("[register] stopping, deregister all the gateway instances")
+			g.registry.deregisterAll()
+			return
+		}
+	}
+}
+
+// SetHosts changes the mesh hosts published through the gateway. It only stores the new hosts, the running
+// registrar deregisters the instances of the removed hosts and registers the added ones.
+func (g *GatewayRegistrar) SetHosts(hosts map[string]PolarisServiceKey) {
+	g.mutex.Lock()
+	g.opts.Hosts = hosts
+	g.mutex.Unlock()
+
+	select {
+	case g.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (g *GatewayRegistrar) deregisterRemovedHosts() {
+	g.mutex.RLock()
+	hosts := g.opts.Hosts
+	g.mutex.RUnlock()
+	for _, host := range g.registry.keys() {
+		if _, exists := hosts[host]; exists {
+			continue
+		}
+		if err := g.registry.sync(host, nil); err != nil {
+			log.Errorf("[register] failed to deregister the gateway for %v: %v", host, err)
+		}
+	}
+}
+
+// sync rediscovers the gateway addresses, and reports the heartbeats only when the gateway has ready endpoints
+func (g *GatewayRegistrar) sync() {
+	svc, err := g.kc.CoreV1().Services(g.namespace).Get(context.TODO(), g.name, v1.GetOptions{})
+	if err != nil {
+		log.Errorf("[register] failed to get the gateway service %v: %v", g.opts.Gateway, err)
+		return
+	}
+	slices, err := g.kc.DiscoveryV1().EndpointSlices(g.namespace).List(context.TODO(), v1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + g.name,
+	})
+	if err != nil {
+		log.Errorf("[register] failed to list the endpoints of the gateway service %v: %v", g.opts.Gateway, err)
+		return
+	}
+
+	healthy := hasReadyEndpoints(slices.Items)
+	if healthy != g.healthy {
+		log.Infof("[register] the gateway %v is healthy: %v", g.opts.Gateway, healthy)
+		g.healthy = healthy
+	}
+
+	g.mutex.RLock()
+	opts := g.opts
+	g.mutex.RUnlock()
+	for host, key := range opts.Hosts {
+		if err := g.registry.sync(host, g.desiredInstances(svc, host, key)); err != nil {
+			log.Errorf("[register] failed to register the gateway for %v: %v", host, err)
+		}
+	}
+	if healthy {
+		g.registry.heartbeat(nil)
+	}
+}
+
+func (g *GatewayRegistrar) desiredInstances(svc *corev1.Service, host string,
+	key PolarisServiceKey) map[string]*polaris.PolarisInstance {
+	instances := make(map[string]*polaris.PolarisInstance)
+	port := gatewayPort(svc, g.opts.PortName)
+	if port == nil {
+		log.Errorf("[register] the gateway %v has no port %v", g.opts.Gateway, g.opts.PortName)
+		return instances
+	}
+
+	protocol := "tcp"
+	if port.AppProtocol != nil {
+		protocol = strings.ToLower(*port.AppProtocol)
+	} else if port.Name != "" {
+		protocol = strings.ToLower(strings.SplitN(port.Name, "-", 2)[0])
+	}
+	addresses := gatewayAddresses(svc)
+	if len(addresses) == 0 {
+		log.Warnf("[register] the gateway %v has no load balancer address or external IP, it is not registered "+
+			"for %v", g.opts.Gateway, host)
+		return instances
+	}
+	for _, address := range addresses {
+		instances[fmt.Sprintf("%s:%d", address, port.Port)] = &polaris.PolarisInstance{
+			Namespace: key.Namespace,
+			Service:   key.Service,
+			Host:      address,
+			Port:      int(port.Port),
+			Protocol:  protocol,
+			Metadata: map[string]string{
+				model.RegisteredByMetadata: model.RegisteredByPolaris2istio,
+				MeshHostMetadata:           host,
+			},
+		}
+	}
+	return instances
+}
+
+func gatewayPort(svc *corev1.Service, name string) *corev1.ServicePort {
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Name == name {
+			return &svc.Spec.Ports[i]
+		}
+	}
+	return nil
+}
+
+// gatewayAddresses prefers the load balancer addresses, then the external IPs. The cluster IP is not used, as it is
+// not reachable by the polaris consumers outside the cluster.
+func gatewayAddresses(svc *corev1.Service) []string {
+	var addresses []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addresses = append(addresses, ingress.IP)
+		} else if ingress.Hostname != "" {
+			addresses = append(addresses, ingress.Hostname)
+		}
+	}
+	if len(addresses) > 0 {
+		return addresses
+	}
+	return svc.Spec.ExternalIPs
+}
+
+func hasReadyEndpoints(slices []discoveryv1.EndpointSlice) bool {
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				return true
+			}
+		}
+	}
+	return false
+}
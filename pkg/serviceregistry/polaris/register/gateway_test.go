@@ -0,0 +1,112 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"testing"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseGatewayHosts(t *testing.T) {
+	assert := assert.New(t)
+	hosts, err := ParseGatewayHosts("reviews.default.svc.cluster.local=Production/reviews")
+	assert.Nil(err)
+	assert.Equal(map[string]PolarisServiceKey{
+		"reviews.default.svc.cluster.local": {Namespace: "Production", Service: "reviews"},
+	}, hosts)
+
+	_, err = ParseGatewayHosts("reviews.default.svc.cluster.local=reviews")
+	assert.NotNil(err)
+}
+
+func TestGatewayDesiredInstances(t *testing.T) {
+	assert := assert.New(t)
+	svc := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Ports: []corev1.ServicePort{
+				{Name: "status-port", Port: 15021},
+				{Name: "http2", Port: 80},
+			},
+		},
+	}
+	g := &GatewayRegistrar{opts: GatewayOptions{
+		PortName: "http2",
+		Hosts:    map[string]PolarisServiceKey{"reviews.mesh": {Namespace: "Production", Service: "reviews"}},
+	}}
+
+	// the cluster IP is not reachable outside the cluster, the gateway is not registered without an address
+	assert.Empty(g.desiredInstances(svc, "reviews.mesh", g.opts.Hosts["reviews.mesh"]))
+
+	svc.Spec.ExternalIPs = []string{"192.168.0.10"}
+	instances := g.desiredInstances(svc, "reviews.mesh", g.opts.Hosts["reviews.mesh"])
+	assert.Len(instances, 1)
+	instance := instances["192.168.0.10:80"]
+	assert.Equal("Production", instance.Namespace)
+	assert.Equal("http2", instance.Protocol)
+	assert.Equal("reviews.mesh", instance.Metadata[MeshHostMetadata])
+	assert.Equal(model.RegisteredByPolaris2istio, instance.Metadata[model.RegisteredByMetadata])
+
+	// the load balancer addresses take precedence over the external IPs
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}, {Hostname: "gw.example.com"}}
+	instances = g.desiredInstances(svc, "reviews.mesh", g.opts.Hosts["reviews.mesh"])
+	assert.Len(instances, 2)
+	assert.Contains(instances, "1.1.1.1:80")
+	assert.Contains(instances, "gw.example.com:80")
+
+	g.opts.PortName = "https"
+	assert.Empty(g.desiredInstances(svc, "reviews.mesh", g.opts.Hosts["reviews.mesh"]))
+}
+
+func TestGatewaySync(t *testing.T) {
+	assert := assert.New(t)
+	svc := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Ports:     []corev1.ServicePort{{Name: "http2", Port: 80}},
+		},
+	}
+	slice := newTestSlice("istio-ingressgateway-1", "endpointslice-controller.k8s.io", true)
+	slice.Namespace = "istio-system"
+	slice.Labels[discoveryv1.LabelServiceName] = "istio-ingressgateway"
+
+	_, err := NewGatewayRegistrar(fake.NewSimpleClientset(), nil, GatewayOptions{Gateway: "istio-ingressgateway"})
+	assert.NotNil(err)
+	g, err := NewGatewayRegistrar(fake.NewSimpleClientset(svc, slice), nil, GatewayOptions{
+		Gateway:  "istio-system/istio-ingressgateway",
+		PortName: "http2",
+		Hosts:    map[string]PolarisServiceKey{"reviews.mesh": {Namespace: "Production", Service: "reviews"}},
+	})
+	assert.Nil(err)
+
+	// the gateway without a load balancer address is healthy, but it is not registered
+	g.sync()
+	assert.True(g.healthy)
+	assert.Empty(g.registry.registered["reviews.mesh"])
+
+	// the changed hosts are only stored, they are applied by the running registrar
+	g.SetHosts(map[string]PolarisServiceKey{"ratings.mesh": {Namespace: "Production", Service: "ratings"}})
+	assert.Len(g.changed, 1)
+	g.SetHosts(map[string]PolarisServiceKey{"ratings.mesh": {Namespace: "Production", Service: "ratings"}})
+	assert.Len(g.changed, 1)
+	assert.Contains(g.opts.Hosts, "ratings.mesh")
+}
@@ -0,0 +1,132 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+const (
+	// KubernetesNamespaceMetadata is the metadata key of the kubernetes namespace of a registered instance
+	KubernetesNamespaceMetadata = "aeraki.net/kubernetesNamespace"
+	// KubernetesServiceMetadata is the metadata key of the kubernetes service of a registered instance
+	KubernetesServiceMetadata = "aeraki.net/kubernetesService"
+)
+
+// ParseNamespaceMapping parses the namespace mapping in the format of <k8s-namespace>=<polaris-namespace>,...
+func ParseNamespaceMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid namespace mapping: %v", item)
+		}
+		mapping[kv[0]] = kv[1]
+	}
+	return mapping, nil
+}
+
+// polarisService maps the kubernetes service to the polaris namespace and service, the annotations of the
+// kubernetes service take precedence over the namespace mapping
+func (o *Options) polarisService(svc *corev1.Service) (string, string) {
+	namespace, exists := svc.Annotations["aeraki.net/polarisNamespace"]
+	if !exists {
+		namespace, exists = o.NamespaceMapping[svc.Namespace]
+	}
+	if !exists {
+		namespace = o.DefaultNamespace
+	}
+	if namespace == "" {
+		namespace = svc.Namespace
+	}
+
+	name, exists := svc.Annotations["aeraki.net/polarisService"]
+	if !exists {
+		name = svc.Name
+	}
+	return namespace, name
+}
+
+// isManagedService returns whether the service is generated by polaris2istio from the polaris,
+// such services must never be registered back into the polaris
+func isManagedService(svc *corev1.Service) bool {
+	return svc.Labels[model.ManagerLabel] == model.AerakiFieldManager
+}
+
+// desiredInstances computes the polaris instances of the kubernetes service from its ready endpoints,
+// the result is keyed by host:port
+func (o *Options) desiredInstances(svc *corev1.Service,
+	slices []*discoveryv1.EndpointSlice) map[string]*polaris.PolarisInstance {
+	instances := make(map[string]*polaris.PolarisInstance)
+	if svc == nil || isManagedService(svc) {
+		return instances
+	}
+
+	namespace, name := o.polarisService(svc)
+	for _, slice := range slices {
+		if slice.Labels[discoveryv1.LabelManagedBy] == model.EndpointSliceManager ||
+			slice.AddressType == discoveryv1.AddressTypeFQDN {
+			continue
+		}
+		for _, port := range slice.Ports {
+			if port.Port == nil {
+				continue
+			}
+			protocol := portProtocol(port)
+			for _, endpoint := range slice.Endpoints {
+				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+					continue
+				}
+				for _, address := range endpoint.Addresses {
+					instance := &polaris.PolarisInstance{
+						Namespace: namespace,
+						Service:   name,
+						Host:      address,
+						Port:      int(*port.Port),
+						Protocol:  protocol,
+						Metadata: map[string]string{
+							model.RegisteredByMetadata:  model.RegisteredByPolaris2istio,
+							KubernetesNamespaceMetadata: svc.Namespace,
+							KubernetesServiceMetadata:   svc.Name,
+						},
+					}
+					instances[fmt.Sprintf("%s:%d", instance.Host, instance.Port)] = instance
+				}
+			}
+		}
+	}
+	return instances
+}
+
+// portProtocol follows the istio protocol selection, the appProtocol takes precedence over the port name prefix
+func portProtocol(port discoveryv1.EndpointPort) string {
+	if port.AppProtocol != nil && *port.AppProtocol != "" {
+		return strings.ToLower(*port.AppProtocol)
+	}
+	if port.Name != nil && *port.Name != "" {
+		return strings.ToLower(strings.SplitN(*port.Name, "-", 2)[0])
+	}
+	return "tcp"
+}
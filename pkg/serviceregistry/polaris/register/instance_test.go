@@ -0,0 +1,98 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseNamespaceMapping(t *testing.T) {
+	assert := assert.New(t)
+	mapping, err := ParseNamespaceMapping("default=Production, dev=Development")
+	assert.Nil(err)
+	assert.Equal(map[string]string{"default": "Production", "dev": "Development"}, mapping)
+
+	mapping, err = ParseNamespaceMapping("")
+	assert.Nil(err)
+	assert.Empty(mapping)
+
+	_, err = ParseNamespaceMapping("default")
+	assert.NotNil(err)
+}
+
+func newTestSlice(name string, managedBy string, ready ...bool) *discoveryv1.EndpointSlice {
+	portName := "http-web"
+	port := int32(8080)
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: "reviews",
+				discoveryv1.LabelManagedBy:   managedBy,
+			},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+	}
+	for i := range ready {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{fmt.Sprintf("10.0.0.%d", i+1)},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready[i]},
+		})
+	}
+	return slice
+}
+
+func TestDesiredInstances(t *testing.T) {
+	assert := assert.New(t)
+	opts := &Options{NamespaceMapping: map[string]string{"default": "Production"}}
+	svc := &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: "reviews", Namespace: "default"}}
+
+	instances := opts.desiredInstances(svc, []*discoveryv1.EndpointSlice{
+		newTestSlice("reviews-1", "endpointslice-controller.k8s.io", true, false),
+		newTestSlice("reviews-2", model.EndpointSliceManager, true),
+	})
+	assert.Len(instances, 1)
+	instance := instances["10.0.0.1:8080"]
+	assert.Equal("Production", instance.Namespace)
+	assert.Equal("reviews", instance.Service)
+	assert.Equal("http", instance.Protocol)
+	assert.Equal(model.RegisteredByPolaris2istio, instance.Metadata[model.RegisteredByMetadata])
+
+	svc.Annotations = map[string]string{
+		"aeraki.net/polarisNamespace": "Test",
+		"aeraki.net/polarisService":   "reviews-mesh",
+	}
+	instances = opts.desiredInstances(svc, []*discoveryv1.EndpointSlice{
+		newTestSlice("reviews-1", "endpointslice-controller.k8s.io", true),
+	})
+	assert.Equal("Test", instances["10.0.0.1:8080"].Namespace)
+	assert.Equal("reviews-mesh", instances["10.0.0.1:8080"].Service)
+
+	// the services generated by polaris2istio are never registered back
+	svc.Labels = map[string]string{model.ManagerLabel: model.AerakiFieldManager}
+	instances = opts.desiredInstances(svc, []*discoveryv1.EndpointSlice{
+		newTestSlice("reviews-1", "endpointslice-controller.k8s.io", true),
+	})
+	assert.Empty(instances)
+}
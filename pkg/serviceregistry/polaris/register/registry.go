@@ -0,0 +1,153 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"sync"
+
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	"istio.io/pkg/log"
+)
+
+// HeartbeatTTL is the ttl in seconds of the registered instances,
+// they are unhealthy if they miss the heartbeats for the ttl
+const HeartbeatTTL = 10
+
+type registeredInstance struct {
+	instance *polaris.PolarisInstance
+	id       string
+}
+
+// registry keeps the instances registered into the polaris, grouped by the keys of their sources
+type registry struct {
+	provider *polaris.PolarisProviderClient
+
+	mutex sync.Mutex
+	// registered instances keyed by the source and host:port
+	registered map[string]map[string]*registeredInstance
+}
+
+func newRegistry(provider *polaris.PolarisProviderClient) *registry {
+	return &registry{
+		provider:   provider,
+		registered: make(map[string]map[string]*registeredInstance),
+	}
+}
+
+// sync registers the new instances and deregisters the removed ones of the source
+func (r *registry) sync(key string, desired map[string]*polaris.PolarisInstance) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	registered := r.registered[key]
+	if registered == nil {
+		registered = make(map[string]*registeredInstance)
+	}
+
+	var errs []error
+	for addr, instance := range desired {
+		if old, exists := registered[addr]; exists {
+			if old.instance.Namespace == instance.Namespace && old.instance.Service == instance.Service &&
+				old.instance.Protocol == instance.Protocol {
+				continue
+			}
+			// the polaris service mapping is changed, move the instance to the new polaris service
+			if err := r.provider.Deregister(old.instance, old.id); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			delete(registered, addr)
+		}
+		id, err := r.provider.Register(instance)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Infof("[register] registered %v:%v into polaris service %v/%v, id: %v",
+			instance.Host, instance.Port, instance.Namespace, instance.Service, id)
+		registered[addr] = &registeredInstance{instance: instance, id: id}
+	}
+
+	for addr, old := range registered {
+		if _, exists := desired[addr]; exists {
+			continue
+		}
+		if err := r.provider.Deregister(old.instance, old.id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Infof("[register] deregistered %v:%v from polaris service %v/%v",
+			old.instance.Host, old.instance.Port, old.instance.Namespace, old.instance.Service)
+		delete(registered, addr)
+	}
+
+	if len(registered) == 0 {
+		delete(r.registered, key)
+	} else {
+		r.registered[key] = registered
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// heartbeat reports the heartbeats of the instances of the sources accepted by the filter,
+// the instances failed to heartbeat are forgotten and the keys of their sources are returned
+// so that they can be registered again
+func (r *registry) heartbeat(filter func(key string) bool) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var failed []string
+	for key, registered := range r.registered {
+		if filter != nil && !filter(key) {
+			continue
+		}
+		for addr, instance := range registered {
+			if err := r.provider.Heartbeat(instance.instance, instance.id); err != nil {
+				log.Warnf("[register] heartbeat of %v %v failed, it will be registered again: %v", key, addr, err)
+				delete(registered, addr)
+				failed = append(failed, key)
+			}
+		}
+	}
+	return failed
+}
+
+// keys returns the keys of the sources which have registered instances
+func (r *registry) keys() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	keys := make([]string, 0, len(r.registered))
+	for key := range r.registered {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (r *registry) deregisterAll() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key, registered := range r.registered {
+		for _, instance := range registered {
+			if err := r.provider.Deregister(instance.instance, instance.id); err != nil {
+				log.Errorf("[register] failed to deregister %v %v:%v: %v", key,
+					instance.instance.Host, instance.instance.Port, err)
+			}
+		}
+	}
+	r.registered = make(map[string]map[string]*registeredInstance)
+}
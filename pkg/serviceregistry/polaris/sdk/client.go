@@ -19,6 +19,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aeraki-mesh/polaris2istio/pkg/health"
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
 	registryModel "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
 	"github.com/polarismesh/polaris-go/api"
 	"github.com/polarismesh/polaris-go/pkg/config"
@@ -35,16 +37,71 @@ const (
 type PolarisClient struct {
 	conn       api.ConsumerAPI
 	polarisMap *sync.Map
+	// cluster is the name of the polaris cluster when the client is federated
+	cluster string
 }
 
 type syncSECallBack func(polarisInfo *registryModel.PolarisInfo)
 
-// NewPolarisClient creates a new client for the polaris
-func NewPolarisClient(polarisAddress string) (*PolarisClient, error) {
-	cf := config.NewDefaultConfiguration([]string{polarisAddress})
+// SDKOptions are the settings passed through to the polaris-go sdk, the zero values keep the defaults of the sdk
+type SDKOptions struct {
+	// Protocol is the protocol to connect to the polaris servers
+	Protocol string
+	// ConnectTimeout is the timeout to connect to the polaris servers
+	ConnectTimeout time.Duration
+	// MessageTimeout is the timeout to wait for the responses of the polaris servers
+	MessageTimeout time.Duration
+	// RequestTimeout is the timeout of the api calls, including the retries
+	RequestTimeout time.Duration
+	// MaxRetryTimes is the max retry times of the api calls
+	MaxRetryTimes int
+	// RetryInterval is the interval between the retries of the api calls
+	RetryInterval time.Duration
+	// CacheDir is the directory of the local cache of the polaris services
+	CacheDir string
+	// ServiceExpireTime is the time after which an unused polaris service is evicted from the local cache
+	ServiceExpireTime time.Duration
+	// ServiceRefreshInterval is the interval to refresh the polaris services in the local cache
+	ServiceRefreshInterval time.Duration
+}
+
+func newConfiguration(polarisAddresses []string, opts SDKOptions) config.Configuration {
+	cf := config.NewDefaultConfiguration(polarisAddresses)
 	cf.Global.ServerConnector.Protocol = defaultProtocol
 	cf.Global.ServerConnector.ConnectTimeout = model.ToDurationPtr(defaultConnectTimeout)
-	conn, err := api.NewConsumerAPIByConfig(cf)
+	if opts.Protocol != "" {
+		cf.Global.ServerConnector.Protocol = opts.Protocol
+	}
+	if opts.ConnectTimeout > 0 {
+		cf.Global.ServerConnector.SetConnectTimeout(opts.ConnectTimeout)
+	}
+	if opts.MessageTimeout > 0 {
+		cf.Global.ServerConnector.SetMessageTimeout(opts.MessageTimeout)
+	}
+	if opts.RequestTimeout > 0 {
+		cf.Global.API.SetTimeout(opts.RequestTimeout)
+	}
+	if opts.MaxRetryTimes > 0 {
+		cf.Global.API.SetMaxRetryTimes(opts.MaxRetryTimes)
+	}
+	if opts.RetryInterval > 0 {
+		cf.Global.API.SetRetryInterval(opts.RetryInterval)
+	}
+	if opts.CacheDir != "" {
+		cf.Consumer.LocalCache.SetPersistDir(opts.CacheDir)
+	}
+	if opts.ServiceExpireTime > 0 {
+		cf.Consumer.LocalCache.SetServiceExpireTime(opts.ServiceExpireTime)
+	}
+	if opts.ServiceRefreshInterval > 0 {
+		cf.Consumer.LocalCache.SetServiceRefreshInterval(opts.ServiceRefreshInterval)
+	}
+	return cf
+}
+
+// NewPolarisClient creates a new client for the polaris servers
+func NewPolarisClient(polarisAddresses []string, opts SDKOptions) (*PolarisClient, error) {
+	conn, err := api.NewConsumerAPIByConfig(newConfiguration(polarisAddresses, opts))
 	if err != nil {
 		return nil, err
 	}
@@ -109,24 +166,34 @@ func (c *PolarisClient) WatchPolarisService(polarisInfo *registryModel.PolarisIn
 
 func (c *PolarisClient) waitForEvents(ch <-chan model.SubScribeEvent, polarisInfo *registryModel.PolarisInfo,
 	cb syncSECallBack, stop <-chan struct{}) {
+	// the same service is watched in each of the federated clusters
+	name := "waitForEvents " + getName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	if c.cluster != "" {
+		name = fmt.Sprintf("waitForEvents %s/%s", c.cluster,
+			getName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService))
+	}
 	for {
 		select {
 		case <-stop:
 			klog.Info("[waitForEvents] stopping")
 			return
-		default:
-			e := <-ch
+		case e := <-ch:
+			// the service is not watched any more once the loop exits, so it is reported unhealthy to be restarted
 			if e == nil {
 				klog.Error("[waitForEvents] has nothing to do, event is nil")
+				health.Default.Fail(name, fmt.Errorf("the event channel is closed"))
 				return
 			}
 			eType := e.GetSubScribeEventType()
 			if eType != api.EventInstance {
 				klog.Errorf("[waitForEvents] has nothing to do, event type is not EventInstance, event type: %v", eType)
+				health.Default.Fail(name, fmt.Errorf("unexpected event type %v", eType))
 				return
 			}
 			insEvent := e.(*model.InstanceEvent)
+			done := health.Default.Busy(name)
 			c.dealEvent(insEvent, polarisInfo, cb)
+			done()
 		}
 	}
 }
@@ -145,15 +212,18 @@ func (c *PolarisClient) dealEvent(ins *model.InstanceEvent, polarisInfo *registr
 
 func (c *PolarisClient) dealAddEvent(polarisInfo *registryModel.PolarisInfo, cb syncSECallBack) {
 	klog.Infof("dealAddEvent polarisInfo %v", polarisInfo)
+	metrics.PolarisEvents.WithLabelValues(metrics.EventAdd).Inc()
 	cb(polarisInfo)
 }
 
 func (c *PolarisClient) dealUpdateEvent(polarisInfo *registryModel.PolarisInfo, cb syncSECallBack) {
 	klog.Infof("dealUpdateEvent polarisInfo %v", polarisInfo)
+	metrics.PolarisEvents.WithLabelValues(metrics.EventUpdate).Inc()
 	cb(polarisInfo)
 }
 
 func (c *PolarisClient) dealDeleteEvent(polarisInfo *registryModel.PolarisInfo, cb syncSECallBack) {
 	klog.Infof("dealDeleteEvent polarisInfo %v", polarisInfo)
+	metrics.PolarisEvents.WithLabelValues(metrics.EventDelete).Inc()
 	cb(polarisInfo)
 }
@@ -15,10 +15,16 @@
 package polarisclient
 
 import (
+	"path/filepath"
+	"sync"
 	"testing"
 
+	"github.com/aeraki-mesh/polaris2istio/pkg/health"
 	mock "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/mock"
+	registryModel "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
 	"github.com/polarismesh/polaris-go/api"
+	"github.com/polarismesh/polaris-go/pkg/model"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestClient(t *testing.T) {
@@ -32,7 +38,8 @@ func TestClient(t *testing.T) {
 	}{
 		{"Testns", "demo", nil},
 	}
-	polarisclient, err := NewPolarisClient(mock.GlobalPolarisMockServer.GetGrpcServerURL())
+	polarisclient, err := NewPolarisClient([]string{mock.GlobalPolarisMockServer.GetGrpcServerURL()},
+		SDKOptions{CacheDir: filepath.Join(testSDKDir, "backup")})
 	if err != nil {
 		t.Errorf("failed to new polaris client consumer client: %v", err)
 	}
@@ -49,3 +56,24 @@ func TestClient(t *testing.T) {
 	}
 
 }
+
+func TestWaitForEventsPerCluster(t *testing.T) {
+	assert := assert.New(t)
+	defaultChecker := health.Default
+	health.Default = health.NewChecker()
+	defer func() { health.Default = defaultChecker }()
+
+	polarisInfo := &registryModel.PolarisInfo{PolarisNamespace: "Production", PolarisService: "reviews"}
+	for _, cluster := range []string{"cluster-a", "cluster-b"} {
+		ch := make(chan model.SubScribeEvent)
+		close(ch)
+		c := &PolarisClient{polarisMap: new(sync.Map), cluster: cluster}
+		c.waitForEvents(ch, polarisInfo, func(*registryModel.PolarisInfo) {}, nil)
+	}
+
+	// the same service watched in several clusters is reported per cluster
+	err := health.Default.Live()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "waitForEvents cluster-a/Production.reviews")
+	assert.Contains(err.Error(), "waitForEvents cluster-b/Production.reviews")
+}
@@ -0,0 +1,280 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	registryModel "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/polarismesh/polaris-go/pkg/model"
+	"k8s.io/klog"
+)
+
+// ErrServiceNotFound is returned when the polaris service does not exist in any of the clusters
+var ErrServiceNotFound = errors.New("polaris service not found")
+
+// ConflictPolicy decides how the instances of the same polaris service in several clusters are merged
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyMerge merges the instances of all the clusters, the first configured cluster wins
+	// when the same host:port is registered in several clusters
+	ConflictPolicyMerge ConflictPolicy = "merge"
+	// ConflictPolicyPriority only keeps the instances of the first configured cluster which has any
+	ConflictPolicyPriority ConflictPolicy = "priority"
+)
+
+// ClusterConfig is the config of a polaris cluster
+type ClusterConfig struct {
+	Name      string
+	Addresses []string
+	Locality  string
+	Network   string
+}
+
+// ParseClusterConfig parses the cluster config in the format of
+// name=<name>,address=<host:port>[,address=<host:port>][,locality=<region/zone/subzone>][,network=<network>]
+func ParseClusterConfig(s string) (ClusterConfig, error) {
+	cluster := ClusterConfig{}
+	for _, item := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			return cluster, fmt.Errorf("invalid polaris cluster config: %v", item)
+		}
+		switch kv[0] {
+		case "name":
+			cluster.Name = kv[1]
+		case "address":
+			cluster.Addresses = append(cluster.Addresses, kv[1])
+		case "locality":
+			cluster.Locality = kv[1]
+		case "network":
+			cluster.Network = kv[1]
+		default:
+			return cluster, fmt.Errorf("unknown polaris cluster config: %v", kv[0])
+		}
+	}
+	if cluster.Name == "" || len(cluster.Addresses) == 0 {
+		return cluster, fmt.Errorf("polaris cluster should have name and address: %v", s)
+	}
+	return cluster, nil
+}
+
+// instancesClient is the client of a polaris cluster used by the federated client
+type instancesClient interface {
+	GetPolarisAllInstances(namespace string, service string) (*model.InstancesResponse, error)
+	WatchPolarisService(polarisInfo *registryModel.PolarisInfo, cb syncSECallBack, force bool,
+		stop <-chan struct{}) error
+}
+
+type clusterClient struct {
+	ClusterConfig
+	client instancesClient
+}
+
+// FederatedClient subscribes to several polaris clusters, and merges the instances of the same polaris service
+type FederatedClient struct {
+	clusters []*clusterClient
+	policy   ConflictPolicy
+	// the last instances successfully got from each cluster, keyed by cluster/namespace/service,
+	// so that the loss of a cluster does not wipe the instances contributed by it
+	lastInstances *sync.Map
+	// the watched polaris services keyed by namespace/service
+	watched *sync.Map
+	// the polaris services watched successfully in each cluster keyed by cluster/namespace/service, the clusters
+	// which failed to watch a polaris service are watched again on the next call
+	watchedClusters *sync.Map
+}
+
+// NewFederatedClient creates a client for the polaris clusters, the clusters are in descending priority
+func NewFederatedClient(clusters []ClusterConfig, policy ConflictPolicy, opts SDKOptions) (*FederatedClient, error) {
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no polaris cluster is configured")
+	}
+	if policy != ConflictPolicyMerge && policy != ConflictPolicyPriority {
+		return nil, fmt.Errorf("unknown conflict policy: %v", policy)
+	}
+
+	c := &FederatedClient{policy: policy, lastInstances: new(sync.Map), watched: new(sync.Map),
+		watchedClusters: new(sync.Map)}
+	names := make(map[string]struct{}, len(clusters))
+	for _, cluster := range clusters {
+		if _, exists := names[cluster.Name]; exists {
+			return nil, fmt.Errorf("duplicated polaris cluster: %v", cluster.Name)
+		}
+		names[cluster.Name] = struct{}{}
+		client, err := NewPolarisClient(cluster.Addresses, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for polaris cluster %v: %v", cluster.Name, err)
+		}
+		client.cluster = cluster.Name
+		c.clusters = append(c.clusters, &clusterClient{ClusterConfig: cluster, client: client})
+	}
+	return c, nil
+}
+
+// GetPolarisAllInstances get all instances with the given name and namespace from all the clusters
+func (c *FederatedClient) GetPolarisAllInstances(namespace string, service string) (*model.InstancesResponse,
+	error) {
+	rsps := make([]*clusterInstances, 0, len(c.clusters))
+	var errs []error
+	notFound := 0
+	for _, cluster := range c.clusters {
+		key := fmt.Sprintf("%s/%s/%s", cluster.Name, namespace, service)
+		rsp, err := cluster.client.GetPolarisAllInstances(namespace, service)
+		metrics.SetPolarisConnected(cluster.Name, err == nil || isServiceNotFound(err))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %v: %v", cluster.Name, err))
+			// the service is deleted from the cluster, its last instances are not served any more
+			if isServiceNotFound(err) {
+				notFound++
+				c.lastInstances.Delete(key)
+				continue
+			}
+			last, exists := c.lastInstances.Load(key)
+			if !exists {
+				continue
+			}
+			klog.Warningf("[GetPolarisAllInstances] query polaris cluster %v failed, use the last instances: %v",
+				cluster.Name, err)
+			rsp = last.(*model.InstancesResponse)
+		} else {
+			c.lastInstances.Store(key, rsp)
+		}
+		rsps = append(rsps, &clusterInstances{cluster: cluster.ClusterConfig, rsp: rsp})
+	}
+
+	if len(rsps) == 0 && notFound == len(c.clusters) {
+		return nil, fmt.Errorf("%w in all the polaris clusters: %v", ErrServiceNotFound, errs)
+	}
+	if len(rsps) == 0 {
+		return nil, fmt.Errorf("query all polaris clusters failed: %v", errs)
+	}
+	return mergeInstances(rsps, c.policy), nil
+}
+
+// WatchPolarisService watch polaris services in all the clusters, it only fails when all the clusters fail.
+// The clusters which failed are watched again on the next call, even if it is not forced.
+func (c *FederatedClient) WatchPolarisService(polarisInfo *registryModel.PolarisInfo, cb syncSECallBack, force bool,
+	stop <-chan struct{}) error {
+	var errs []error
+	watched := 0
+	for _, cluster := range c.clusters {
+		clusterKey := fmt.Sprintf("%s/%s/%s", cluster.Name, polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+		_, exists := c.watchedClusters.Load(clusterKey)
+		if exists && !force {
+			watched++
+			continue
+		}
+		// the cluster which failed is forced, so that it is not skipped as a service already watched by its client
+		err := cluster.client.WatchPolarisService(polarisInfo, cb, force || !exists, stop)
+		metrics.SetPolarisConnected(cluster.Name, err == nil)
+		if err != nil {
+			klog.Errorf("[WatchPolarisService] watch polaris cluster %v failed, err: %v", cluster.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		c.watchedClusters.Store(clusterKey, struct{}{})
+		watched++
+	}
+	if watched == 0 {
+		return fmt.Errorf("watch all polaris clusters failed: %v", errs)
+	}
+	key := polarisInfo.PolarisNamespace + "/" + polarisInfo.PolarisService
+	if _, loaded := c.watched.LoadOrStore(key, struct{}{}); !loaded {
+		metrics.WatchedServices.Inc()
+	}
+	return nil
+}
+
+// PingClusters returns nil if any of the polaris clusters accepts the connection, the clusters connected through
+// the secure proxies are pinged through the unix sockets of the proxies
+func PingClusters(clusters []ClusterConfig, timeout time.Duration) error {
+	var errs []error
+	for _, cluster := range clusters {
+		for _, address := range cluster.Addresses {
+			network := "tcp"
+			if strings.HasPrefix(address, "unix://") {
+				network, address = "unix", strings.TrimPrefix(address, "unix://")
+			}
+			conn, err := net.DialTimeout(network, address, timeout)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cluster %v: %v", cluster.Name, err))
+				continue
+			}
+			_ = conn.Close()
+			return nil
+		}
+	}
+	return fmt.Errorf("no polaris cluster is connected: %v", errs)
+}
+
+type clusterInstances struct {
+	cluster ClusterConfig
+	rsp     *model.InstancesResponse
+}
+
+// isServiceNotFound returns whether the error of the sdk is returned because the polaris service does not exist
+func isServiceNotFound(err error) bool {
+	sdkErr, ok := err.(model.SDKError)
+	return ok && sdkErr.ErrorCode() == model.ErrCodeServiceNotFound
+}
+
+// mergeInstances merges the instances of the clusters into one response, the revision is derived from
+// the revisions of all the clusters
+func mergeInstances(rsps []*clusterInstances, policy ConflictPolicy) *model.InstancesResponse {
+	out := &model.InstancesResponse{ServiceInfo: rsps[0].rsp.ServiceInfo}
+	hosts := make(map[string]struct{})
+	revisions := make([]string, 0, len(rsps))
+
+	for _, rsp := range rsps {
+		revisions = append(revisions, rsp.cluster.Name+":"+rsp.rsp.GetRevision())
+		if policy == ConflictPolicyPriority && len(out.Instances) > 0 {
+			continue
+		}
+		for _, instance := range rsp.rsp.Instances {
+			addr := fmt.Sprintf("%s:%d", instance.GetHost(), instance.GetPort())
+			if _, exists := hosts[addr]; exists {
+				klog.Infof("[mergeInstances] instance %v of service %v in cluster %v is ignored, it's in another cluster",
+					addr, rsp.rsp.GetService(), rsp.cluster.Name)
+				continue
+			}
+			hosts[addr] = struct{}{}
+			out.Instances = append(out.Instances, &registryModel.ClusterInstance{
+				Instance: instance,
+				Cluster:  rsp.cluster.Name,
+				Locality: rsp.cluster.Locality,
+				Network:  rsp.cluster.Network,
+			})
+			out.TotalWeight += instance.GetWeight()
+		}
+	}
+
+	if len(rsps) == 1 {
+		out.Revision = rsps[0].rsp.GetRevision()
+	} else {
+		sum := sha1.Sum([]byte(strings.Join(revisions, ",")))
+		out.Revision = hex.EncodeToString(sum[:])
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	registryModel "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/polarismesh/polaris-go/pkg/model"
+	"github.com/polarismesh/polaris-go/pkg/model/local"
+	"github.com/polarismesh/polaris-go/pkg/model/pb"
+	namingpb "github.com/polarismesh/polaris-go/pkg/model/pb/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestInstance(host string, port uint32) model.Instance {
+	return pb.NewInstanceInProto(&namingpb.Instance{
+		Id:     &wrappers.StringValue{Value: host},
+		Host:   &wrappers.StringValue{Value: host},
+		Port:   &wrappers.UInt32Value{Value: port},
+		Weight: &wrappers.UInt32Value{Value: 100},
+	}, &model.ServiceKey{Namespace: "Test", Service: "rating"}, local.NewInstanceLocalValue())
+}
+
+func newTestClusterInstances(cluster, revision string, instances ...model.Instance) *clusterInstances {
+	return &clusterInstances{
+		cluster: ClusterConfig{Name: cluster, Locality: cluster + "/zone-1", Network: cluster + "-network"},
+		rsp: &model.InstancesResponse{
+			ServiceInfo: model.ServiceInfo{Namespace: "Test", Service: "rating"},
+			Revision:    revision,
+			Instances:   instances,
+		},
+	}
+}
+
+// fakeClusterClient serves the instances of a polaris cluster from memory
+type fakeClusterClient struct {
+	rsp      *model.InstancesResponse
+	err      error
+	watchErr error
+	watches  int
+}
+
+func (c *fakeClusterClient) GetPolarisAllInstances(namespace string, service string) (*model.InstancesResponse,
+	error) {
+	return c.rsp, c.err
+}
+
+func (c *fakeClusterClient) WatchPolarisService(polarisInfo *registryModel.PolarisInfo, cb syncSECallBack,
+	force bool, stop <-chan struct{}) error {
+	c.watches++
+	return c.watchErr
+}
+
+func newTestFederatedClient(clients map[string]*fakeClusterClient, names ...string) *FederatedClient {
+	c := &FederatedClient{policy: ConflictPolicyMerge, lastInstances: new(sync.Map), watched: new(sync.Map),
+		watchedClusters: new(sync.Map)}
+	for _, name := range names {
+		c.clusters = append(c.clusters, &clusterClient{ClusterConfig: ClusterConfig{Name: name}, client: clients[name]})
+	}
+	return c
+}
+
+func TestParseClusterConfig(t *testing.T) {
+	assert := assert.New(t)
+	cluster, err := ParseClusterConfig(
+		"name=gz,address=10.0.0.1:8091,address=10.0.0.2:8091,locality=ap-guangzhou/zone-1,network=gz")
+	assert.Nil(err)
+	assert.Equal(ClusterConfig{
+		Name:      "gz",
+		Addresses: []string{"10.0.0.1:8091", "10.0.0.2:8091"},
+		Locality:  "ap-guangzhou/zone-1",
+		Network:   "gz",
+	}, cluster)
+
+	_, err = ParseClusterConfig("name=gz")
+	assert.NotNil(err)
+	_, err = ParseClusterConfig("name=gz,address=10.0.0.1:8091,zone=1")
+	assert.NotNil(err)
+}
+
+func TestMergeInstances(t *testing.T) {
+	assert := assert.New(t)
+	rsps := []*clusterInstances{
+		newTestClusterInstances("gz", "rev-1", newTestInstance("10.0.0.1", 8080)),
+		newTestClusterInstances("sh", "rev-2", newTestInstance("10.0.0.1", 8080), newTestInstance("10.1.0.1", 8080)),
+	}
+
+	merged := mergeInstances(rsps, ConflictPolicyMerge)
+	assert.Len(merged.Instances, 2)
+	assert.Equal(uint32(200), uint32(merged.TotalWeight))
+	first := merged.Instances[0].(*registryModel.ClusterInstance)
+	assert.Equal("gz", first.Cluster)
+	assert.Equal("gz/zone-1", first.Locality)
+	assert.Equal("gz", first.GetMetadata()[registryModel.PolarisClusterLabel])
+	second := merged.Instances[1].(*registryModel.ClusterInstance)
+	assert.Equal("10.1.0.1", second.GetHost())
+	assert.Equal("sh-network", second.Network)
+
+	// the revision changes with any of the clusters
+	revision := merged.Revision
+	rsps[1].rsp.Revision = "rev-3"
+	assert.NotEqual(revision, mergeInstances(rsps, ConflictPolicyMerge).Revision)
+
+	merged = mergeInstances(rsps, ConflictPolicyPriority)
+	assert.Len(merged.Instances, 1)
+	assert.Equal("gz", merged.Instances[0].(*registryModel.ClusterInstance).Cluster)
+
+	// the next cluster takes over when the first one has no instances
+	rsps[0].rsp.Instances = nil
+	merged = mergeInstances(rsps, ConflictPolicyPriority)
+	assert.Len(merged.Instances, 2)
+	assert.Equal("sh", merged.Instances[0].(*registryModel.ClusterInstance).Cluster)
+}
+
+func TestIsServiceNotFound(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(isServiceNotFound(model.NewSDKError(model.ErrCodeServiceNotFound, nil,
+		"service Production/reviews not found")))
+	assert.False(isServiceNotFound(model.NewSDKError(model.ErrCodeNetworkError, nil, "connection refused")))
+	assert.False(isServiceNotFound(fmt.Errorf("service not found")))
+}
+
+func TestGetPolarisAllInstancesServiceDeleted(t *testing.T) {
+	assert := assert.New(t)
+	clients := map[string]*fakeClusterClient{
+		"gz": {rsp: newTestClusterInstances("gz", "rev-1", newTestInstance("10.0.0.1", 8080)).rsp},
+		"sh": {rsp: newTestClusterInstances("sh", "rev-2", newTestInstance("10.1.0.1", 8080)).rsp},
+	}
+	c := newTestFederatedClient(clients, "gz", "sh")
+	rsp, err := c.GetPolarisAllInstances("Test", "rating")
+	assert.Nil(err)
+	assert.Len(rsp.Instances, 2)
+
+	// the last instances of a cluster which is unreachable are kept
+	clients["sh"].err = model.NewSDKError(model.ErrCodeNetworkError, nil, "connection refused")
+	rsp, err = c.GetPolarisAllInstances("Test", "rating")
+	assert.Nil(err)
+	assert.Len(rsp.Instances, 2)
+
+	// the instances of a cluster which the service is deleted from are removed
+	clients["sh"].err = model.NewSDKError(model.ErrCodeServiceNotFound, nil, "service Test/rating not found")
+	rsp, err = c.GetPolarisAllInstances("Test", "rating")
+	assert.Nil(err)
+	assert.Len(rsp.Instances, 1)
+	assert.Equal("10.0.0.1", rsp.Instances[0].GetHost())
+
+	// the cached instances are not served again when the cluster is unreachable afterwards
+	clients["sh"].err = model.NewSDKError(model.ErrCodeNetworkError, nil, "connection refused")
+	rsp, err = c.GetPolarisAllInstances("Test", "rating")
+	assert.Nil(err)
+	assert.Len(rsp.Instances, 1)
+
+	clients["gz"].err = model.NewSDKError(model.ErrCodeServiceNotFound, nil, "service Test/rating not found")
+	clients["sh"].err = model.NewSDKError(model.ErrCodeServiceNotFound, nil, "service Test/rating not found")
+	_, err = c.GetPolarisAllInstances("Test", "rating")
+	assert.True(errors.Is(err, ErrServiceNotFound))
+}
+
+func TestWatchPolarisServiceRetriesFailedClusters(t *testing.T) {
+	assert := assert.New(t)
+	clients := map[string]*fakeClusterClient{
+		"gz": {},
+		"sh": {watchErr: fmt.Errorf("connection refused")},
+	}
+	c := newTestFederatedClient(clients, "gz", "sh")
+	polarisInfo := &registryModel.PolarisInfo{PolarisNamespace: "Test", PolarisService: "rating"}
+	cb := func(*registryModel.PolarisInfo) {}
+
+	assert.Nil(c.WatchPolarisService(polarisInfo, cb, false, nil))
+	assert.Equal(1, clients["gz"].watches)
+	assert.Equal(1, clients["sh"].watches)
+
+	// only the cluster which failed is watched again
+	clients["sh"].watchErr = nil
+	assert.Nil(c.WatchPolarisService(polarisInfo, cb, false, nil))
+	assert.Equal(1, clients["gz"].watches)
+	assert.Equal(2, clients["sh"].watches)
+
+	assert.Nil(c.WatchPolarisService(polarisInfo, cb, false, nil))
+	assert.Equal(2, clients["sh"].watches)
+
+	clients["gz"].watchErr = fmt.Errorf("connection refused")
+	clients["sh"].watchErr = fmt.Errorf("connection refused")
+	assert.NotNil(c.WatchPolarisService(&registryModel.PolarisInfo{PolarisNamespace: "Test",
+		PolarisService: "reviews"}, cb, false, nil))
+}
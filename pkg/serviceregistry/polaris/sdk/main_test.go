@@ -0,0 +1,45 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/polarismesh/polaris-go/api"
+)
+
+// testSDKDir holds the logs and the local cache of the polaris sdk in the tests, the sdk writes them to the
+// working directory by default
+var testSDKDir string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "polaris-sdk")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create the directory of the polaris sdk: %v\n", err)
+		os.Exit(1)
+	}
+	testSDKDir = dir
+	if err := api.SetLoggersDir(filepath.Join(dir, "log")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set the log directory of the polaris sdk: %v\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,105 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"github.com/polarismesh/polaris-go/api"
+	"github.com/polarismesh/polaris-go/pkg/model"
+)
+
+// PolarisInstance is an instance to register into the polaris
+type PolarisInstance struct {
+	Namespace string
+	Service   string
+	Host      string
+	Port      int
+	Protocol  string
+	Weight    int
+	Metadata  map[string]string
+}
+
+// PolarisProviderClient is a client for registering instances into the polaris
+type PolarisProviderClient struct {
+	conn api.ProviderAPI
+	ttl  int
+}
+
+// NewPolarisProviderClient creates a new provider client for the polaris, the registered instances
+// are expected to heartbeat within the ttl seconds
+func NewPolarisProviderClient(polarisAddresses []string, ttl int, opts SDKOptions) (*PolarisProviderClient, error) {
+	conn, err := api.NewProviderAPIByConfig(newConfiguration(polarisAddresses, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return &PolarisProviderClient{
+		conn: conn,
+		ttl:  ttl,
+	}, nil
+}
+
+// GetConn get the connection of the client
+func (c *PolarisProviderClient) GetConn() api.ProviderAPI {
+	return c.conn
+}
+
+// Register registers the instance into the polaris and returns the instance id
+func (c *PolarisProviderClient) Register(instance *PolarisInstance) (string, error) {
+	req := &api.InstanceRegisterRequest{}
+	req.Namespace = instance.Namespace
+	req.Service = instance.Service
+	req.Host = instance.Host
+	req.Port = instance.Port
+	req.Metadata = instance.Metadata
+	req.TTL = &c.ttl
+	if instance.Protocol != "" {
+		req.Protocol = &instance.Protocol
+	}
+	if instance.Weight > 0 {
+		req.Weight = &instance.Weight
+	}
+	rsp, err := c.conn.Register(req)
+	if err != nil {
+		return "", err
+	}
+	return rsp.InstanceID, nil
+}
+
+// Deregister deregisters the instance from the polaris
+func (c *PolarisProviderClient) Deregister(instance *PolarisInstance, instanceID string) error {
+	return c.conn.Deregister(&api.InstanceDeRegisterRequest{InstanceDeRegisterRequest: model.InstanceDeRegisterRequest{
+		Namespace:  instance.Namespace,
+		Service:    instance.Service,
+		InstanceID: instanceID,
+		Host:       instance.Host,
+		Port:       instance.Port,
+	}})
+}
+
+// Heartbeat reports the heartbeat of the instance to the polaris
+func (c *PolarisProviderClient) Heartbeat(instance *PolarisInstance, instanceID string) error {
+	return c.conn.Heartbeat(&api.InstanceHeartbeatRequest{InstanceHeartbeatRequest: model.InstanceHeartbeatRequest{
+		Namespace:  instance.Namespace,
+		Service:    instance.Service,
+		InstanceID: instanceID,
+		Host:       instance.Host,
+		Port:       instance.Port,
+	}})
+}
+
+// Destroy releases the resources of the client
+func (c *PolarisProviderClient) Destroy() {
+	c.conn.Destroy()
+}
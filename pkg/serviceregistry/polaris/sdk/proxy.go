@@ -0,0 +1,270 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+// the old upstream connection is closed after the grace period when the credentials are rotated,
+// so that the inflight requests are not interrupted
+const connectionGracePeriod = time.Minute
+
+// SecureProxy is a local grpc proxy which forwards the plaintext requests of the polaris sdk to the polaris server
+// over TLS, with the access token. The polaris sdk only supports the plaintext grpc without credentials,
+// so it connects to the polaris server through the proxy. The proxy listens on a unix socket which is only
+// accessible to the user of the process, as it adds the access token to every request it forwards.
+type SecureProxy struct {
+	upstream string
+	opts     SecurityOptions
+	source   credentialSource
+	dir      string
+	listener net.Listener
+	server   *grpc.Server
+
+	mutex sync.RWMutex
+	creds *serverCredentials
+	conn  *grpc.ClientConn
+}
+
+// NewSecureProxy creates a proxy to the polaris server and starts to serve on a unix socket in a private
+// temporary directory
+func NewSecureProxy(upstream string, opts SecurityOptions) (*SecureProxy, error) {
+	if opts.ReloadInterval <= 0 {
+		opts.ReloadInterval = defaultReloadInterval
+	}
+	source, err := newCredentialSource(&opts)
+	if err != nil {
+		return nil, err
+	}
+	p := &SecureProxy{upstream: upstream, opts: opts, source: source}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	if err := p.listen(); err != nil {
+		p.conn.Close()
+		return nil, err
+	}
+	p.server = grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(p.handleStream))
+	go func() {
+		if err := p.server.Serve(p.listener); err != nil {
+			klog.Errorf("[SecureProxy] proxy of polaris server %v stopped: %v", p.upstream, err)
+		}
+	}()
+	klog.Infof("[SecureProxy] proxy polaris server %v on %v, tls: %v, token: %v",
+		upstream, p.Address(), p.creds.tls, p.creds.token)
+	return p, nil
+}
+
+// listen creates the unix socket of the proxy, the directory and the socket are only accessible to the user
+func (p *SecureProxy) listen() error {
+	dir, err := ioutil.TempDir("", "polaris-proxy")
+	if err != nil {
+		return fmt.Errorf("failed to create the directory of the proxy socket: %v", err)
+	}
+	path := filepath.Join(dir, "polaris.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to listen on the proxy socket: %v", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to restrict the permissions of the proxy socket: %v", err)
+	}
+	p.dir = dir
+	p.listener = listener
+	return nil
+}
+
+// Address returns the address of the unix socket of the proxy, which the polaris sdk connects to
+func (p *SecureProxy) Address() string {
+	return "unix://" + p.listener.Addr().String()
+}
+
+// Run reloads the credentials when they are rotated, the proxy is stopped when the stop channel is closed
+func (p *SecureProxy) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(p.opts.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.reload(); err != nil {
+				klog.Errorf("[SecureProxy] failed to reload the credentials of polaris server %v, "+
+					"keep using the old ones: %v", p.upstream, err)
+			}
+		case <-stop:
+			p.server.Stop()
+			p.mutex.Lock()
+			p.conn.Close()
+			p.mutex.Unlock()
+			os.RemoveAll(p.dir)
+			return
+		}
+	}
+}
+
+// reload loads the credentials, and reconnects to the polaris server if they are changed
+func (p *SecureProxy) reload() error {
+	creds, err := p.source.load()
+	if err != nil {
+		return err
+	}
+	p.mutex.RLock()
+	changed := p.creds == nil || !p.creds.equal(creds)
+	p.mutex.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if creds.tls {
+		cfg, err := creds.tlsConfig(p.opts.ServerName)
+		if err != nil {
+			return err
+		}
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(grpccredentials.NewTLS(cfg))}
+	}
+	conn, err := grpc.Dial(p.upstream, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to polaris server %v: %v", p.upstream, err)
+	}
+
+	p.mutex.Lock()
+	old := p.conn
+	p.creds = creds
+	p.conn = conn
+	p.mutex.Unlock()
+	if old != nil {
+		klog.Infof("[SecureProxy] the credentials of polaris server %v are rotated", p.upstream)
+		time.AfterFunc(connectionGracePeriod, func() {
+			old.Close()
+		})
+	}
+	return nil
+}
+
+func (p *SecureProxy) current() (*grpc.ClientConn, Secret) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.conn, p.creds.token
+}
+
+// handleStream forwards a request of the polaris sdk to the polaris server
+func (p *SecureProxy) handleStream(srv interface{}, serverStream grpc.ServerStream) error {
+	method, ok := grpc.MethodFromServerStream(serverStream)
+	if !ok {
+		return status.Error(codes.Internal, "unknown method")
+	}
+	md, _ := metadata.FromIncomingContext(serverStream.Context())
+	md = md.Copy()
+	conn, token := p.current()
+	if token != "" {
+		md.Set(TokenHeader, token.Value())
+	}
+
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(serverStream.Context(), md))
+	defer cancel()
+	clientStream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, method,
+		grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			f := &frame{}
+			if err := serverStream.RecvMsg(f); err != nil {
+				if err == io.EOF {
+					clientStream.CloseSend()
+				} else {
+					cancel()
+				}
+				return
+			}
+			if err := clientStream.SendMsg(f); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; ; i++ {
+		f := &frame{}
+		if err := clientStream.RecvMsg(f); err != nil {
+			serverStream.SetTrailer(clientStream.Trailer())
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if i == 0 {
+			if header, err := clientStream.Header(); err == nil {
+				if err := serverStream.SendHeader(header); err != nil {
+					return err
+				}
+			}
+		}
+		if err := serverStream.SendMsg(f); err != nil {
+			return err
+		}
+	}
+}
+
+// frame is a raw grpc message forwarded by the proxy
+type frame struct {
+	payload []byte
+}
+
+// rawCodec passes the grpc messages through without decoding them
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	f, ok := v.(*frame)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type: %T", v)
+	}
+	return f.payload, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	f, ok := v.(*frame)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", v)
+	}
+	f.payload = append(f.payload[:0], data...)
+	return nil
+}
+
+// Name is the name of the proto codec, so that the content type of the requests is kept
+func (rawCodec) Name() string {
+	return "proto"
+}
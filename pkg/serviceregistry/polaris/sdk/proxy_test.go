@@ -0,0 +1,178 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTestCertificate creates a self-signed certificate of localhost
+func newTestCertificate(t *testing.T) ([]byte, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	cert, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certPEM, cert
+}
+
+// newTestUpstream starts a TLS server which echoes the requests with the access token
+func newTestUpstream(t *testing.T, cert tls.Certificate) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(
+		grpc.Creds(grpccredentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}})),
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+			md, _ := metadata.FromIncomingContext(stream.Context())
+			tokens := md.Get(TokenHeader)
+			if len(tokens) == 0 {
+				return status.Error(codes.Unauthenticated, "no token")
+			}
+			f := &frame{}
+			if err := stream.RecvMsg(f); err != nil {
+				return err
+			}
+			return stream.SendMsg(&frame{payload: []byte(tokens[0] + ":" + string(f.payload))})
+		}))
+	go server.Serve(listener)
+	return listener.Addr().String(), server.Stop
+}
+
+func invoke(address string, request string) (string, error) {
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply := &frame{}
+	if err := conn.Invoke(ctx, "/v1.PolarisGRPC/Discover", &frame{payload: []byte(request)}, reply,
+		grpc.ForceCodec(rawCodec{})); err != nil {
+		return "", err
+	}
+	return string(reply.payload), nil
+}
+
+func TestSecureProxy(t *testing.T) {
+	assert := assert.New(t)
+	ca, cert := newTestCertificate(t)
+	upstream, stopUpstream := newTestUpstream(t, cert)
+	defer stopUpstream()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	tokenFile := filepath.Join(dir, "token")
+	assert.Nil(ioutil.WriteFile(caFile, ca, 0600))
+	assert.Nil(ioutil.WriteFile(tokenFile, []byte("token-1\n"), 0600))
+
+	proxy, err := NewSecureProxy(upstream, SecurityOptions{CAFile: caFile, TokenFile: tokenFile, ServerName: "localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go proxy.Run(stop)
+
+	// the proxy socket is only accessible to the user
+	assert.True(strings.HasPrefix(proxy.Address(), "unix://"))
+	info, err := os.Stat(strings.TrimPrefix(proxy.Address(), "unix://"))
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(proxy.dir)
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0700), info.Mode().Perm())
+	assert.Nil(PingClusters([]ClusterConfig{{Name: "default", Addresses: []string{proxy.Address()}}}, time.Second))
+
+	reply, err := invoke(proxy.Address(), "hello")
+	assert.Nil(err)
+	assert.Equal("token-1:hello", reply)
+
+	// the rotated token is used after the credentials are reloaded
+	assert.Nil(ioutil.WriteFile(tokenFile, []byte("token-2"), 0600))
+	assert.Nil(proxy.reload())
+	reply, err = invoke(proxy.Address(), "hello")
+	assert.Nil(err)
+	assert.Equal("token-2:hello", reply)
+
+	// a broken CA bundle is not loaded, and the old credentials are kept
+	assert.Nil(ioutil.WriteFile(caFile, []byte("broken"), 0600))
+	assert.NotNil(proxy.reload())
+	reply, err = invoke(proxy.Address(), "hello")
+	assert.Nil(err)
+	assert.Equal("token-2:hello", reply)
+}
+
+func TestSecretRedacted(t *testing.T) {
+	assert := assert.New(t)
+	secret := Secret("token-1")
+	creds := &serverCredentials{key: "key-1", token: secret}
+	for _, format := range []string{"%v", "%s", "%q", "%+v", "%#v"} {
+		assert.False(strings.Contains(fmt.Sprintf(format, secret), "token-1"), format)
+		assert.False(strings.Contains(fmt.Sprintf(format, creds), "token-1"), format)
+		assert.False(strings.Contains(fmt.Sprintf(format, creds), "key-1"), format)
+	}
+	assert.Equal("token-1", secret.Value())
+}
@@ -0,0 +1,222 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	// TokenHeader is the grpc header of the polaris access token
+	TokenHeader = "X-Polaris-Token"
+
+	// the keys of the credentials in the kubernetes secret
+	secretCAKey    = "ca.crt"
+	secretCertKey  = "tls.crt"
+	secretKeyKey   = "tls.key"
+	secretTokenKey = "token"
+
+	defaultReloadInterval = 30 * time.Second
+)
+
+// Secret is a sensitive value, it is redacted when it is printed
+type Secret string
+
+// String redacts the secret
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "******"
+}
+
+// GoString redacts the secret
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+// Value returns the secret in plain text
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// SecurityOptions are the credentials used to connect to the polaris server, they are loaded from
+// the files or a kubernetes secret and reloaded when they are rotated
+type SecurityOptions struct {
+	// TLS connects to the polaris server over TLS, it is implied by the CA and the client certificate
+	TLS bool
+	// CAFile is the CA bundle to verify the polaris server, the system roots are used if it is empty
+	CAFile string
+	// CertFile and KeyFile are the client certificate and key
+	CertFile string
+	KeyFile  string
+	// TokenFile contains the access token of the polaris server
+	TokenFile string
+	// Secret is the namespace/name of a kubernetes secret with the ca.crt, tls.crt, tls.key and token keys,
+	// it is used instead of the files
+	Secret string
+	// ServerName overrides the server name to verify the certificate of the polaris server
+	ServerName string
+	// ReloadInterval is the interval to check whether the credentials are rotated
+	ReloadInterval time.Duration
+}
+
+// Enabled returns whether any credential is configured
+func (o SecurityOptions) Enabled() bool {
+	return o.TLS || o.CAFile != "" || o.CertFile != "" || o.TokenFile != "" || o.Secret != ""
+}
+
+// serverCredentials are the loaded credentials of the polaris server
+type serverCredentials struct {
+	tls   bool
+	ca    []byte
+	cert  []byte
+	key   Secret
+	token Secret
+}
+
+// String redacts the key and the token, fmt prints the unexported fields without their String methods
+func (c *serverCredentials) String() string {
+	return fmt.Sprintf("{tls: %v, ca: %d bytes, cert: %d bytes, key: %v, token: %v}",
+		c.tls, len(c.ca), len(c.cert), c.key, c.token)
+}
+
+// GoString redacts the key and the token
+func (c *serverCredentials) GoString() string {
+	return c.String()
+}
+
+func (c *serverCredentials) equal(o *serverCredentials) bool {
+	return c.tls == o.tls && string(c.ca) == string(o.ca) && string(c.cert) == string(o.cert) &&
+		c.key == o.key && c.token == o.token
+}
+
+func (c *serverCredentials) tlsConfig(serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if len(c.ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.ca) {
+			return nil, fmt.Errorf("no valid certificate in the CA bundle")
+		}
+		cfg.RootCAs = pool
+	}
+	if len(c.cert) > 0 || c.key != "" {
+		cert, err := tls.X509KeyPair(c.cert, []byte(c.key.Value()))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// credentialSource loads the credentials of the polaris server
+type credentialSource interface {
+	load() (*serverCredentials, error)
+}
+
+func newCredentialSource(opts *SecurityOptions) (credentialSource, error) {
+	if opts.Secret == "" {
+		return &fileSource{opts: opts}, nil
+	}
+
+	parts := strings.SplitN(opts.Secret, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid polaris secret, it should be namespace/name: %v", opts.Secret)
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	kc, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &secretSource{kc: kc, tls: opts.TLS, namespace: parts[0], name: parts[1]}, nil
+}
+
+// fileSource loads the credentials from the files, such as a mounted kubernetes secret
+type fileSource struct {
+	opts *SecurityOptions
+}
+
+func (s *fileSource) load() (*serverCredentials, error) {
+	c := &serverCredentials{tls: s.opts.TLS || s.opts.CAFile != "" || s.opts.CertFile != ""}
+	var err error
+	if c.ca, err = readFile(s.opts.CAFile); err != nil {
+		return nil, err
+	}
+	if c.cert, err = readFile(s.opts.CertFile); err != nil {
+		return nil, err
+	}
+	key, err := readFile(s.opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	c.key = Secret(key)
+	token, err := readFile(s.opts.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	c.token = Secret(strings.TrimSpace(string(token)))
+	return c, nil
+}
+
+func readFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %v", path, err)
+	}
+	return data, nil
+}
+
+// secretSource loads the credentials from a kubernetes secret
+type secretSource struct {
+	kc        kubernetes.Interface
+	tls       bool
+	namespace string
+	name      string
+}
+
+func (s *secretSource) load() (*serverCredentials, error) {
+	secret, err := s.kc.CoreV1().Secrets(s.namespace).Get(context.TODO(), s.name, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %v/%v: %v", s.namespace, s.name, err)
+	}
+	c := &serverCredentials{
+		ca:    secret.Data[secretCAKey],
+		cert:  secret.Data[secretCertKey],
+		key:   Secret(secret.Data[secretKeyKey]),
+		token: Secret(strings.TrimSpace(string(secret.Data[secretTokenKey]))),
+	}
+	c.tls = s.tls || len(c.ca) > 0 || len(c.cert) > 0
+	return c, nil
+}
@@ -0,0 +1,114 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/polarismesh/polaris-go/pkg/model"
+)
+
+const (
+	// servicesPath is the path of the open API listing the services of the polaris server
+	servicesPath = "/naming/v1/services"
+	// servicesPageSize is the number of the services listed in a request
+	servicesPageSize = 100
+	// executeSuccess is the code of the successful responses of the open API
+	executeSuccess = 200000
+)
+
+// servicesResponse is the response of the services open API
+type servicesResponse struct {
+	Code     uint32 `json:"code"`
+	Info     string `json:"info"`
+	Amount   uint32 `json:"amount"`
+	Services []struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Metadata  map[string]string `json:"metadata"`
+	} `json:"services"`
+}
+
+// ListServices lists the services of the polaris namespace with the open API of the polaris server, the grpc API
+// used by the sdk can only list the services by business or metadata. The address is the HTTP address of the
+// polaris server, the token is sent if it is not empty. The services are sorted by the name.
+func ListServices(ctx context.Context, client *http.Client, address string, token Secret,
+	namespace string) ([]model.ServiceInfo, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	endpoint := strings.TrimSuffix(address, "/") + servicesPath
+
+	var services []model.ServiceInfo
+	for offset := 0; ; offset += servicesPageSize {
+		query := url.Values{}
+		query.Set("namespace", namespace)
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(servicesPageSize))
+		rsp, err := getServices(ctx, client, endpoint+"?"+query.Encode(), token)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range rsp.Services {
+			services = append(services, model.ServiceInfo{
+				Service:   service.Name,
+				Namespace: service.Namespace,
+				Metadata:  service.Metadata,
+			})
+		}
+		if len(rsp.Services) < servicesPageSize || len(services) >= int(rsp.Amount) {
+			break
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Service < services[j].Service })
+	return services, nil
+}
+
+func getServices(ctx context.Context, client *http.Client, url string, token Secret) (*servicesResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set(TokenHeader, token.Value())
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the polaris services: %v", err)
+	}
+	defer rsp.Body.Close()
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the polaris services: %v", err)
+	}
+
+	out := &servicesResponse{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("failed to list the polaris services, status %v: %s", rsp.StatusCode, body)
+	}
+	if rsp.StatusCode != http.StatusOK || out.Code != executeSuccess {
+		return nil, fmt.Errorf("failed to list the polaris services, status %v, code %v: %v", rsp.StatusCode,
+			out.Code, out.Info)
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polarisclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListServices(t *testing.T) {
+	assert := assert.New(t)
+
+	total := 150
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != servicesPath || r.Header.Get(TokenHeader) != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code": 401000, "info": "access is not approved"}`)
+			return
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		services := []map[string]interface{}{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			services = append(services, map[string]interface{}{
+				"name":      fmt.Sprintf("svc-%03d", total-i),
+				"namespace": r.URL.Query().Get("namespace"),
+				"metadata":  map[string]string{"team": "foo"},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": executeSuccess, "amount": total, "size": len(services), "services": services,
+		})
+	}))
+	defer server.Close()
+
+	services, err := ListServices(context.TODO(), server.Client(), server.URL, "secret", "Test")
+	assert.Nil(err)
+	assert.Len(services, total)
+	assert.Equal("svc-001", services[0].Service)
+	assert.Equal("Test", services[0].Namespace)
+	assert.Equal(map[string]string{"team": "foo"}, services[0].Metadata)
+
+	_, err = ListServices(context.TODO(), server.Client(), server.URL, "", "Test")
+	assert.Contains(err.Error(), "access is not approved")
+}
@@ -0,0 +1,105 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polaris
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/apis/polaris/v1alpha1"
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/pkg/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// getBindingList lists the PolarisServiceBindings, it is empty if the CRD is not installed
+func (w *ProviderWatcher) getBindingList() ([]v1alpha1.PolarisServiceBinding, error) {
+	if w.bc == nil {
+		return nil, nil
+	}
+	bindings, err := w.bc.PolarisV1alpha1().PolarisServiceBindings(w.configRootNS).List(context.TODO(),
+		v1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debugf("the PolarisServiceBinding CRD is not installed, only the annotations are used")
+			return nil, nil
+		}
+		log.Errorf("Error list polaris service bindings: %v", err)
+		return nil, err
+	}
+	return bindings.Items, nil
+}
+
+// createBoundServiceEntry creates the ServiceEntry of the polaris service declared by the binding, it is owned by
+// the binding so that it is garbage collected with the binding
+func (w *ProviderWatcher) createBoundServiceEntry(bindingName string, rsp *polarismodel.InstancesResponse,
+	polarisInfo *model.PolarisInfo, spec *istio.ServiceEntry, annotations map[string]string) error {
+	binding, err := w.bc.PolarisV1alpha1().PolarisServiceBindings(w.configRootNS).Get(context.TODO(), bindingName,
+		v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get polaris service binding failed, error: %v", err)
+	}
+	owner := *v1.NewControllerRef(binding, v1alpha1.SchemeGroupVersion.WithKind("PolarisServiceBinding"))
+	// the WorkloadEntries are owned by the binding as the ServiceEntry does not exist yet
+	if polarisInfo.IsWorkloadEntryMode() {
+		if err := w.syncWorkloadEntries(rsp, owner); err != nil {
+			return fmt.Errorf("failed to sync WorkloadEntries: %w", err)
+		}
+	}
+
+	serviceEntry := w.toServiceEntryCRD(model.CovertServiceName(polarisInfo.PolarisNamespace,
+		polarisInfo.PolarisService), spec, nil, annotations)
+	serviceEntry.OwnerReferences = []v1.OwnerReference{owner}
+	if w.dryRun != nil {
+		w.dryRun.Create("serviceentries", serviceEntry)
+		return nil
+	}
+	klog.Infof("[syncPolarisServices2Istio] create serviceentry of binding %v: %v", bindingName, spec)
+	_, err = w.ic.NetworkingV1alpha3().ServiceEntries(w.configRootNS).Create(context.TODO(), serviceEntry,
+		v1.CreateOptions{FieldManager: model.AerakiFieldManager})
+	if err != nil {
+		return fmt.Errorf("failed to create ServiceEntry: %v", metrics.KubernetesWriteError("serviceentries", err))
+	}
+	return nil
+}
+
+// writeBindingStatus writes the sync status of the polaris service to the status of its binding
+func (w *ProviderWatcher) writeBindingStatus(name string, result syncResult, syncErr error) {
+	if w.dryRun != nil {
+		return
+	}
+	client := w.bc.PolarisV1alpha1().PolarisServiceBindings(w.configRootNS)
+	binding, err := client.Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		klog.Warningf("[writeSyncStatus] failed to get the polaris service binding %v: %v", name, err)
+		return
+	}
+	status, changed := nextSyncStatus(model.GetSyncStatusFromBinding(binding), binding.Generation, result, syncErr)
+	if !changed {
+		return
+	}
+	binding.Status = status.BindingStatus()
+	// the resource version makes the update fail if the binding is changed since it is got
+	if _, err := client.UpdateStatus(context.TODO(), binding,
+		v1.UpdateOptions{FieldManager: model.AerakiFieldManager}); err != nil {
+		klog.Warningf("[writeSyncStatus] failed to write the sync status of binding %v: %v", name,
+			metrics.KubernetesWriteError("polarisservicebindings", err))
+	}
+}
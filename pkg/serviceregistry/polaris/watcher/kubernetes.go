@@ -0,0 +1,338 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polaris
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// syncKubernetesService projects the polaris service into a selector-less Service and its EndpointSlices,
+// the unchanged revision is not skipped if force is true
+func (w *ProviderWatcher) syncKubernetesService(rsp *polarismodel.InstancesResponse, force bool) error {
+	newService, newSlices := model.ConvertKubernetesService(rsp, w.targetNS)
+	w.converted(rsp, &convertedObjects{Object: newService.DeepCopy(), EndpointSlices: newSlices})
+	client := w.kc.CoreV1().Services(w.targetNS)
+
+	return w.upsertProjection(rsp, force, &projection{
+		resource:         "services",
+		serviceNameLabel: discoveryv1.LabelServiceName,
+		apiVersion:       "v1",
+		kind:             "Service",
+		object:           newService,
+		slices:           newSlices,
+		get: func() (v1.Object, error) {
+			oldService, err := client.Get(context.TODO(), newService.Name, v1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return oldService, nil
+		},
+		create: func() (v1.Object, error) {
+			return client.Create(context.TODO(), newService, v1.CreateOptions{FieldManager: model.AerakiFieldManager})
+		},
+		update: func() error {
+			_, err := client.Update(context.TODO(), newService, v1.UpdateOptions{FieldManager: model.AerakiFieldManager})
+			return err
+		},
+		// a ClusterIP Service without ports is rejected, and the cluster IP can not be dropped to make it headless,
+		// so the service scaled to zero keeps its last ports and it is created with its first instances
+		prepare: func(old v1.Object) bool {
+			if len(newService.Spec.Ports) > 0 {
+				return true
+			}
+			if old == nil {
+				klog.Infof("[syncKubernetesService] service %v has no instances, it is not created yet",
+					newService.Name)
+				return false
+			}
+			newService.Spec.Ports = old.(*corev1.Service).Spec.Ports
+			return true
+		},
+		specEqual: func(old v1.Object) bool {
+			return equality.Semantic.DeepEqual(old.(*corev1.Service).Spec.Ports, newService.Spec.Ports)
+		},
+		merge: func(old v1.Object) {
+			mergeServiceSpec(newService, old.(*corev1.Service))
+		},
+	})
+}
+
+// projection is the object which a polaris service is projected into along with its EndpointSlices,
+// the Service of the method 2 or the ServiceImport of the method 3
+type projection struct {
+	// resource is the plural name of the object in the logs, the metrics and the dry run
+	resource string
+	// serviceNameLabel is the label of the EndpointSlices naming the object
+	serviceNameLabel string
+	// apiVersion and kind of the object, which owns its EndpointSlices
+	apiVersion string
+	kind       string
+	object     v1.Object
+	slices     []*discoveryv1.EndpointSlice
+
+	get    func() (v1.Object, error)
+	create func() (v1.Object, error)
+	update func() error
+	// prepare completes the new object with the old one, which is nil if it does not exist,
+	// nothing is written if it returns false
+	prepare func(old v1.Object) bool
+	// specEqual returns whether the old object has the spec of the new one
+	specEqual func(old v1.Object) bool
+	// merge keeps the fields of the old object allocated by kubernetes
+	merge func(old v1.Object)
+}
+
+// upsertProjection creates or updates the projected object and its EndpointSlices, the unchanged revision is not
+// skipped if force is true
+func (w *ProviderWatcher) upsertProjection(rsp *polarismodel.InstancesResponse, force bool, p *projection) error {
+	name := p.object.GetName()
+	old, err := p.get()
+	notFound := errors.IsNotFound(err)
+	if err != nil && !notFound {
+		return fmt.Errorf("get %v %v failed: %v", p.resource, name, err)
+	}
+	if !notFound && !force && old.GetAnnotations()["aeraki.net/revision"] == rsp.GetRevision() {
+		klog.Infof("[upsertProjection] %v unchanged: %v", p.resource, name)
+		return nil
+	}
+	if p.prepare != nil && !p.prepare(old) {
+		return nil
+	}
+
+	if notFound && w.dryRun != nil {
+		if err := w.syncEndpointSlices(projectionSliceSelector(p.serviceNameLabel, name), p.slices); err != nil {
+			return err
+		}
+		w.dryRun.Create(p.resource, p.object)
+		return nil
+	}
+	// the EndpointSlices are owned by the object, so the new object is created first without the revision, which is
+	// written once the EndpointSlices are synced so that they are retried on failure
+	if notFound {
+		klog.Infof("[upsertProjection] create %v: %v", p.resource, name)
+		annotations := p.object.GetAnnotations()
+		p.object.SetAnnotations(withoutRevision(annotations))
+		created, err := p.create()
+		p.object.SetAnnotations(annotations)
+		if err != nil {
+			return metrics.KubernetesWriteError(p.resource, err)
+		}
+		old = created
+	}
+
+	owner := v1.OwnerReference{APIVersion: p.apiVersion, Kind: p.kind, Name: name, UID: old.GetUID()}
+	for _, slice := range p.slices {
+		slice.OwnerReferences = []v1.OwnerReference{owner}
+	}
+	if err := w.syncEndpointSlices(projectionSliceSelector(p.serviceNameLabel, name), p.slices); err != nil {
+		return err
+	}
+
+	if objectMetaEqual(old, p.object) && p.specEqual(old) {
+		klog.Infof("[upsertProjection] %v unchanged: %v", p.resource, name)
+		return nil
+	}
+	p.object.SetResourceVersion(old.GetResourceVersion())
+	p.merge(old)
+	if w.dryRun != nil {
+		w.dryRun.Update(p.resource, old, p.object)
+		return nil
+	}
+	klog.Infof("[upsertProjection] update %v: %v", p.resource, name)
+	return metrics.KubernetesWriteError(p.resource, p.update())
+}
+
+// withoutRevision returns a copy of the annotations without the revision
+func withoutRevision(annotations map[string]string) map[string]string {
+	copied := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		if k != "aeraki.net/revision" {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// projectionSliceSelector selects the EndpointSlices generated for the projected object
+func projectionSliceSelector(serviceNameLabel, name string) string {
+	return fmt.Sprintf("%s=%s, %s=%s", model.ManagerLabel, model.AerakiFieldManager, serviceNameLabel, name)
+}
+
+// cleanKubernetesServices deletes the Services projected from the polaris services which are not declared any more
+func (w *ProviderWatcher) cleanKubernetesServices(declared map[string]bool) error {
+	client := w.kc.CoreV1().Services(w.targetNS)
+	list, err := client.List(context.TODO(), v1.ListOptions{LabelSelector: projectionSelector()})
+	if err != nil {
+		return fmt.Errorf("list services failed: %v", err)
+	}
+	var errs []error
+	for i := range list.Items {
+		svc := &list.Items[i]
+		if !w.isOrphanProjection(svc, declared) {
+			continue
+		}
+		if err := w.deleteProjection("services", discoveryv1.LabelServiceName, svc, func() error {
+			return client.Delete(context.TODO(), svc.Name, v1.DeleteOptions{})
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("clean services failed: %v", errs)
+	}
+	return nil
+}
+
+// projectionSelector selects the objects projected from the polaris services
+func projectionSelector() string {
+	return fmt.Sprintf("%s=%s, %s=%s, %s", model.ManagerLabel, model.AerakiFieldManager,
+		model.RegistryLabel, model.PolarisRegistry, model.PolarisServiceLabel)
+}
+
+// isOrphanProjection returns whether the projected object is not declared by any source, and is written by this
+// replica
+func (w *ProviderWatcher) isOrphanProjection(object v1.Object, declared map[string]bool) bool {
+	polarisInfo := &model.PolarisInfo{
+		PolarisNamespace: object.GetAnnotations()["aeraki.net/polarisNamespace"],
+		PolarisService:   object.GetAnnotations()["aeraki.net/polarisService"],
+	}
+	if polarisInfo.PolarisNamespace == "" || polarisInfo.PolarisService == "" ||
+		declared[polarisInfo.PolarisNamespace+"/"+polarisInfo.PolarisService] {
+		return false
+	}
+	return w.skipReason(polarisInfo) == ""
+}
+
+// deleteProjection deletes the projected object and its EndpointSlices, the EndpointSlices are garbage collected
+// with their owner, but the ones written without an owner are deleted as well
+func (w *ProviderWatcher) deleteProjection(resource, serviceNameLabel string, object v1.Object,
+	delete func() error) error {
+	if err := w.syncEndpointSlices(projectionSliceSelector(serviceNameLabel, object.GetName()), nil); err != nil {
+		return err
+	}
+	if w.dryRun != nil {
+		w.dryRun.Delete(resource, object)
+		return nil
+	}
+	klog.Infof("[deleteProjection] delete %v: %v", resource, object.GetName())
+	if err := delete(); err != nil && !errors.IsNotFound(err) {
+		return metrics.KubernetesWriteError(resource, err)
+	}
+	return nil
+}
+
+// objectMetaEqual returns whether the old object has the labels and the annotations of the new one
+func objectMetaEqual(old v1.Object, new v1.Object) bool {
+	for k, v := range new.GetLabels() {
+		if old.GetLabels()[k] != v {
+			return false
+		}
+	}
+	for k, v := range new.GetAnnotations() {
+		if old.GetAnnotations()[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeServiceSpec keeps the fields allocated by kubernetes
+func mergeServiceSpec(new *corev1.Service, old *corev1.Service) {
+	new.Spec.ClusterIP = old.Spec.ClusterIP
+	new.Spec.ClusterIPs = old.Spec.ClusterIPs
+	new.Spec.IPFamilies = old.Spec.IPFamilies
+	new.Spec.IPFamilyPolicy = old.Spec.IPFamilyPolicy
+}
+
+// syncEndpointSlices creates, updates and deletes the EndpointSlices selected by the given label selector
+func (w *ProviderWatcher) syncEndpointSlices(selector string, newSlices []*discoveryv1.EndpointSlice) error {
+	client := w.kc.DiscoveryV1().EndpointSlices(w.targetNS)
+	oldList, err := client.List(context.TODO(), v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("list endpoint slices failed: %v", err)
+	}
+
+	olds := make(map[string]*discoveryv1.EndpointSlice, len(oldList.Items))
+	for i := range oldList.Items {
+		olds[oldList.Items[i].Name] = &oldList.Items[i]
+	}
+
+	var errs []error
+	for _, slice := range newSlices {
+		old, exists := olds[slice.Name]
+		delete(olds, slice.Name)
+		if !exists {
+			if w.dryRun != nil {
+				w.dryRun.Create("endpointslices", slice)
+				continue
+			}
+			klog.Infof("[syncEndpointSlices] create endpointslice: %v", slice.Name)
+			if _, err := client.Create(context.TODO(), slice,
+				v1.CreateOptions{FieldManager: model.AerakiFieldManager}); err != nil {
+				errs = append(errs, metrics.KubernetesWriteError("endpointslices", err))
+			}
+			continue
+		}
+		if endpointSliceEqual(old, slice) {
+			continue
+		}
+		slice.ResourceVersion = old.ResourceVersion
+		if w.dryRun != nil {
+			w.dryRun.Update("endpointslices", old, slice)
+			continue
+		}
+		klog.Infof("[syncEndpointSlices] update endpointslice: %v", slice.Name)
+		if _, err := client.Update(context.TODO(), slice,
+			v1.UpdateOptions{FieldManager: model.AerakiFieldManager}); err != nil {
+			errs = append(errs, metrics.KubernetesWriteError("endpointslices", err))
+		}
+	}
+
+	for name, old := range olds {
+		if w.dryRun != nil {
+			w.dryRun.Delete("endpointslices", old)
+			continue
+		}
+		klog.Infof("[syncEndpointSlices] delete endpointslice: %v", name)
+		if err := client.Delete(context.TODO(), name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, metrics.KubernetesWriteError("endpointslices", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("sync endpoint slices failed: %v", errs)
+	}
+	return nil
+}
+
+// endpointSliceEqual compares the owners too, so that the EndpointSlices written without an owner are adopted
+func endpointSliceEqual(old *discoveryv1.EndpointSlice, new *discoveryv1.EndpointSlice) bool {
+	return old.AddressType == new.AddressType &&
+		equality.Semantic.DeepEqual(old.Endpoints, new.Endpoints) &&
+		equality.Semantic.DeepEqual(old.Ports, new.Ports) &&
+		equality.Semantic.DeepEqual(old.Labels, new.Labels) &&
+		equality.Semantic.DeepEqual(old.OwnerReferences, new.OwnerReferences)
+}
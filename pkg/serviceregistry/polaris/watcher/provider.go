@@ -16,72 +16,254 @@ package polaris
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 
 	istio "istio.io/api/networking/v1alpha3"
 	"istio.io/client-go/pkg/apis/networking/v1alpha3"
 
 	// "istio.io/client-go/pkg/apis/networking/v1beta1"
+	bindingclient "github.com/aeraki-mesh/polaris2istio/pkg/client/clientset/versioned"
+	"github.com/aeraki-mesh/polaris2istio/pkg/debug"
+	"github.com/aeraki-mesh/polaris2istio/pkg/dryrun"
+	"github.com/aeraki-mesh/polaris2istio/pkg/events"
+	"github.com/aeraki-mesh/polaris2istio/pkg/freshness"
+	"github.com/aeraki-mesh/polaris2istio/pkg/health"
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
 	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
 	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
 	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	mcsclient "sigs.k8s.io/mcs-api/pkg/client/clientset/versioned"
 
 	"istio.io/pkg/log"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 )
 
-const (
-	// aerakiFieldManager is the FileldManager for Aeraki CRDs
-	aerakiFieldManager = "aeraki"
-)
-
 // ProviderWatcher is a watcher for polaris
 type ProviderWatcher struct {
-	polarisclient *polaris.PolarisClient
-	ic            *istioclient.Clientset
-	configRootNS  string
+	polarisclient  *polaris.FederatedClient
+	ic             *istioclient.Clientset
+	kc             kubernetes.Interface
+	mc             mcsclient.Interface
+	bc             bindingclient.Interface
+	configRootNS   string
+	registryMethod uint
+	targetNS       string
+	isLeader       func() bool
+	owns           func(namespace, service string) bool
+	freshness      *freshness.Tracker
+	syncState      *debug.Store
+	events         *events.Recorder
+	dryRun         *dryrun.Recorder
+	sources        *sync.Map
+	synced         *sync.Map
+	subscribed     *sync.Map
 }
 
 // NewProviderWatcher creates a ProviderWatcher
-func NewProviderWatcher(ic *istioclient.Clientset, polarisclient *polaris.PolarisClient,
-	configRootNS string) *ProviderWatcher {
+func NewProviderWatcher(ic *istioclient.Clientset, kc kubernetes.Interface, mc mcsclient.Interface,
+	polarisclient *polaris.FederatedClient, configRootNS string, registryMethod uint, targetNS string) *ProviderWatcher {
 	return &ProviderWatcher{
-		polarisclient: polarisclient,
-		ic:            ic,
-		configRootNS:  configRootNS,
+		polarisclient:  polarisclient,
+		ic:             ic,
+		kc:             kc,
+		mc:             mc,
+		configRootNS:   configRootNS,
+		registryMethod: registryMethod,
+		targetNS:       targetNS,
 	}
 }
 
 // Run scan services, then  registered or update polaris services to the istio mesh
 func (w *ProviderWatcher) Run(stop <-chan struct{}) {
+	services, err := w.listServices()
+	if err != nil {
+		return
+	}
+	health.Default.Met(health.ConditionServiceEntries)
+	w.cleanProjections(services)
+
+	watched := true
+	for _, service := range services {
+		polarisInfo := service.polarisInfo
+		// the events and the sync status of the polaris service are recorded on its source
+		w.trackSource(polarisInfo, service.name)
+		if w.events != nil {
+			w.events.Track(polarisInfo.PolarisNamespace, polarisInfo.PolarisService, service.object)
+		}
+
+		err = w.polarisclient.WatchPolarisService(polarisInfo, w.syncPolarisServices2Istio, service.force, stop)
+		if w.syncState != nil {
+			w.syncState.Subscribed(polarisInfo.PolarisNamespace, polarisInfo.PolarisService, err)
+		}
+		if err != nil {
+			log.Errorf("Watch polaris %v failed, error: %v", polarisInfo, err)
+			if w.skipReason(polarisInfo) == "" {
+				err = fmt.Errorf("failed to watch the polaris service: %v", err)
+				w.recordSyncEvent(polarisInfo, "", err)
+				w.writeSyncStatus(polarisInfo, syncResult{}, err)
+			}
+			watched = false
+			continue
+		}
+		if service.binding != "" && w.subscribed != nil {
+			w.subscribed.Store(service.binding, struct{}{})
+		}
+	}
+	// the services are synced when they are watched for the first time, the failed syncs are retried by the
+	// events and the next scans
+	if watched && w.allSynced(services) {
+		health.Default.Met(health.ConditionInitialSync)
+	}
+}
+
+// markSynced records that the first sync of the polaris service is done by this replica
+func (w *ProviderWatcher) markSynced(polarisInfo *model.PolarisInfo) {
+	if w.synced != nil {
+		w.synced.Store(polarisInfo.PolarisNamespace+"/"+polarisInfo.PolarisService, struct{}{})
+	}
+}
+
+// allSynced returns whether the first syncs of all the polaris services are done
+func (w *ProviderWatcher) allSynced(services []declaredService) bool {
+	if w.synced == nil {
+		return true
+	}
+	for _, service := range services {
+		if _, exists := w.synced.Load(service.polarisInfo.PolarisNamespace + "/" +
+			service.polarisInfo.PolarisService); !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanProjections deletes the objects projected from the polaris services which are not declared any more, the
+// objects of the services written by the other replicas are left to them
+func (w *ProviderWatcher) cleanProjections(services []declaredService) {
+	declared := make(map[string]bool, len(services))
+	for _, service := range services {
+		declared[service.polarisInfo.PolarisNamespace+"/"+service.polarisInfo.PolarisService] = true
+	}
+	var err error
+	switch w.registryMethod {
+	case model.RegistryMethodKubernetesService:
+		err = w.cleanKubernetesServices(declared)
+	case model.RegistryMethodServiceImport:
+		err = w.cleanServiceImports(declared)
+	}
+	if err != nil {
+		log.Errorf("Clean the projections of the removed polaris services failed: %v", err)
+	}
+}
+
+// declaredService is a polaris service declared by a PolarisServiceBinding or by the annotations of a ServiceEntry
+type declaredService struct {
+	polarisInfo *model.PolarisInfo
+	// name and object are the source which declares the polaris service
+	name   string
+	object runtime.Object
+	// binding is the uid of the PolarisServiceBinding which declares the polaris service
+	binding types.UID
+	// force watches the polaris service again until it is synced
+	force bool
+}
+
+// listServices lists the polaris services declared by the PolarisServiceBindings and the matched ServiceEntries,
+// the binding wins if a polaris service is declared by both
+func (w *ProviderWatcher) listServices() ([]declaredService, error) {
 	seList, err := w.getServiceEntryList()
 	if err != nil {
 		log.Errorf("Error getting service entry list: %v", err)
+		return nil, err
 	}
 
-	for _, se := range seList.Items {
+	services := make([]declaredService, 0, len(seList.Items))
+	bound := make(map[string]bool)
+	// the services declared by the bindings must not be taken as removed when the bindings fail to be listed
+	bindings, err := w.getBindingList()
+	if err != nil {
+		return nil, err
+	}
+	for i := range bindings {
+		binding := &bindings[i]
+		polarisInfo, err := model.GetPolarisInfoFromBinding(binding)
+		if err != nil {
+			log.Errorf("Error get PolarisServiceBinding's spec: %v", err)
+			w.recordInvalid(binding, events.ReasonInvalidBinding, err)
+			continue
+		}
+		bound[polarisInfo.PolarisNamespace+"/"+polarisInfo.PolarisService] = true
+		services = append(services, declaredService{
+			polarisInfo: polarisInfo,
+			name:        binding.Name,
+			object:      binding,
+			binding:     binding.UID,
+			// the binding is watched once, the events of the subscription sync it afterwards
+			force: !w.isSubscribed(binding.UID),
+		})
+	}
+
+	for i := range seList.Items {
+		se := &seList.Items[i]
 		log.Debugf("ServiceEntry [name]: %v [namespace]: %v [hosts]: %v, [endpoints]: %s",
 			se.Name, se.Namespace, se.Spec.Hosts, se.Spec.Endpoints)
 		polarisInfo, err := model.GetPolarisInfoFromSEAnnotations(se.GetAnnotations())
 		if err != nil {
 			log.Errorf("Error get ServiceEntry's annotations: %v", err)
+			w.recordInvalid(se, events.ReasonInvalidAnnotations, err)
+			continue
+		}
+		// the ServiceEntries generated for the bindings are matched as well
+		if bound[polarisInfo.PolarisNamespace+"/"+polarisInfo.PolarisService] {
+			log.Debugf("ServiceEntry %v is declared by a PolarisServiceBinding, skip its annotations", se.Name)
 			continue
 		}
 
 		_, existsRevision := se.GetAnnotations()["aeraki.net/revision"]
 		_, existsExternal := se.GetAnnotations()["aeraki.net/external"]
+		services = append(services, declaredService{
+			polarisInfo: polarisInfo,
+			name:        se.Name,
+			object:      se,
+			force:       !(existsRevision && existsExternal),
+		})
+	}
+	return services, nil
+}
 
-		if err := w.polarisclient.WatchPolarisService(polarisInfo, w.syncPolarisServices2Istio,
-			!(existsRevision && existsExternal), stop); err != nil {
-			log.Errorf("Watch polaris %v failed, error: %v", polarisInfo, err)
-			continue
-		}
+// isSubscribed returns whether the polaris service of the binding is watched
+func (w *ProviderWatcher) isSubscribed(binding types.UID) bool {
+	if w.subscribed == nil {
+		return false
+	}
+	_, exists := w.subscribed.Load(binding)
+	return exists
+}
+
+// recordInvalid records the invalid declaration of the polaris service on its source
+func (w *ProviderWatcher) recordInvalid(object runtime.Object, reason string, err error) {
+	if w.events != nil && (w.isLeader == nil || w.isLeader()) {
+		w.events.Event(object, corev1.EventTypeWarning, reason, err.Error())
 	}
 }
 
 func (w *ProviderWatcher) getServiceEntryList() (*v1alpha3.ServiceEntryList, error) {
 	services, err := w.ic.NetworkingV1alpha3().ServiceEntries(w.configRootNS).List(context.TODO(), v1.ListOptions{
-		LabelSelector: "manager=" + aerakiFieldManager + ", registry=polaris",
+		LabelSelector: model.ManagerLabel + "=" + model.AerakiFieldManager + ", " +
+			model.RegistryLabel + "=" + model.PolarisRegistry,
 	})
 
 	if err != nil {
@@ -92,42 +274,244 @@ func (w *ProviderWatcher) getServiceEntryList() (*v1alpha3.ServiceEntryList, err
 	return services, nil
 }
 
+// Resync converts all the matched polaris services again, and only writes the configs which are changed,
+// it is used when the conversion options are changed
+func (w *ProviderWatcher) Resync() {
+	services, err := w.listServices()
+	if err != nil {
+		return
+	}
+	for _, service := range services {
+		w.trackSource(service.polarisInfo, service.name)
+		w.syncPolarisService(service.polarisInfo, true)
+	}
+}
+
 func (w *ProviderWatcher) syncPolarisServices2Istio(polarisInfo *model.PolarisInfo) {
+	w.syncPolarisService(polarisInfo, false)
+}
+
+// syncPolarisService syncs the polaris service when its revision is changed, or when its configs are changed
+// if force is true
+func (w *ProviderWatcher) syncPolarisService(polarisInfo *model.PolarisInfo, force bool) {
 	klog.Infof("[syncPolarisServices2Istio] polarisInfo: %v", polarisInfo)
-	rsp, err := w.polarisclient.GetPolarisAllInstances(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	if reason := w.skipReason(polarisInfo); reason != "" {
+		klog.V(4).Infof("[syncPolarisServices2Istio] %s, skip writing %v", reason, polarisInfo)
+		w.forget(polarisInfo, reason)
+		// the services written by the other replicas do not hold the readiness of this replica
+		w.markSynced(polarisInfo)
+		return
+	}
+	start := time.Now()
+	result, err := w.syncInstances(polarisInfo, force)
+	metrics.ObserveSync(registryMethodName(w.registryMethod), start, err)
+	if w.syncState != nil {
+		result := debug.ResultSuccess
+		if err != nil {
+			result = debug.ResultFailure
+		}
+		w.syncState.Written(polarisInfo.PolarisNamespace, polarisInfo.PolarisService, result, err)
+	}
+	w.recordSyncEvent(polarisInfo, result.revision, err)
+	w.writeSyncStatus(polarisInfo, result, err)
 	if err != nil {
-		klog.Errorf("[syncPolarisServices2Istio] query polaris services' instances failed, err: %v", err.Error())
+		klog.Errorf("[syncPolarisServices2Istio] %v", err)
 		return
 	}
+	w.markSynced(polarisInfo)
+}
+
+// skipReason returns why the configs of the polaris service are not written by this replica, it is empty
+// if they are written by this replica
+func (w *ProviderWatcher) skipReason(polarisInfo *model.PolarisInfo) string {
+	// the standbys only keep the subscriptions warm, the leader resyncs all the services when it takes over
+	if w.isLeader != nil && !w.isLeader() {
+		return "not the leader"
+	}
+	// the other replicas write the services out of the shards of this replica
+	if w.owns != nil && !w.owns(polarisInfo.PolarisNamespace, polarisInfo.PolarisService) {
+		return "not in the shards of this replica"
+	}
+	return ""
+}
+
+// recordSyncEvent records the outcome of the sync on the source of the polaris service
+func (w *ProviderWatcher) recordSyncEvent(polarisInfo *model.PolarisInfo, revision string, err error) {
+	if w.events == nil {
+		return
+	}
+	namespace, service := polarisInfo.PolarisNamespace, polarisInfo.PolarisService
+	switch {
+	case err == nil:
+		w.events.ServiceEvent(namespace, service, corev1.EventTypeNormal, events.ReasonSynced,
+			fmt.Sprintf("Synced revision %s of polaris service %s/%s to %s", revision, namespace, service,
+				registryMethodName(w.registryMethod)))
+	case errors.Is(err, polaris.ErrServiceNotFound):
+		w.events.ServiceEvent(namespace, service, corev1.EventTypeWarning, events.ReasonPolarisServiceNotFound,
+			fmt.Sprintf("Polaris service %s/%s is not found in any of the polaris clusters", namespace, service))
+	case errors.Is(err, model.ErrInvalidConversion):
+		w.events.ServiceEvent(namespace, service, corev1.EventTypeWarning, events.ReasonConversionFailed, err.Error())
+	default:
+		w.events.ServiceEvent(namespace, service, corev1.EventTypeWarning, events.ReasonSyncFailed, err.Error())
+	}
+}
+
+// forget stops tracking the freshness of the service written by another replica
+func (w *ProviderWatcher) forget(polarisInfo *model.PolarisInfo, reason string) {
+	if w.freshness != nil {
+		w.freshness.Forget(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	}
+	if w.syncState != nil {
+		w.syncState.Written(polarisInfo.PolarisNamespace, polarisInfo.PolarisService, debug.ResultSkipped,
+			fmt.Errorf("%s", reason))
+	}
+}
+
+// converted records the configs converted from the instances of the polaris service for debugging
+func (w *ProviderWatcher) converted(rsp *polarismodel.InstancesResponse, converted interface{}) {
+	if w.syncState != nil {
+		w.syncState.Converted(rsp.GetNamespace(), rsp.GetService(), converted)
+	}
+}
+
+// convertedObjects are the kubernetes objects converted from the instances of the polaris service
+type convertedObjects struct {
+	Object         interface{}                  `json:"object"`
+	EndpointSlices []*discoveryv1.EndpointSlice `json:"endpointSlices"`
+}
+
+// registryMethodName is the name of the registry method in the metrics
+func registryMethodName(method uint) string {
+	switch method {
+	case model.RegistryMethodKubernetesService:
+		return "kubernetes"
+	case model.RegistryMethodServiceImport:
+		return "serviceimport"
+	default:
+		return "serviceentry"
+	}
+}
+
+// syncResult is what the sync got from the polaris
+type syncResult struct {
+	revision  string
+	endpoints model.EndpointCounts
+}
+
+func (w *ProviderWatcher) syncInstances(polarisInfo *model.PolarisInfo, force bool) (result syncResult,
+	err error) {
+	rsp, err := w.polarisclient.GetPolarisAllInstances(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	if err != nil {
+		return result, fmt.Errorf("query polaris services' instances failed, err: %w", err)
+	}
+	total := len(rsp.GetInstances())
+	// the instances registered from the mesh must not be projected back into it
+	rsp = model.FilterInstances(rsp)
+	result = syncResult{revision: rsp.GetRevision(), endpoints: model.CountEndpoints(total, rsp)}
+	if w.syncState != nil {
+		w.syncState.Received(polarisInfo.PolarisNamespace, polarisInfo.PolarisService, total, rsp)
+	}
+	if w.freshness != nil {
+		w.freshness.Observe(polarisInfo.PolarisNamespace, polarisInfo.PolarisService, rsp.GetRevision())
+		// the revision is written when the sync succeeds, or it is already written. Nothing is written in the dry run.
+		defer func() {
+			if err == nil && w.dryRun == nil {
+				w.freshness.Written(polarisInfo.PolarisNamespace, polarisInfo.PolarisService, rsp.GetRevision())
+			}
+		}()
+	}
+	metrics.ServiceEndpoints.WithLabelValues(polarisInfo.PolarisNamespace, polarisInfo.PolarisService).
+		Set(float64(len(rsp.GetInstances())))
+
+	switch w.registryMethod {
+	case model.RegistryMethodKubernetesService:
+		if err := w.syncKubernetesService(rsp, force); err != nil {
+			return result, fmt.Errorf("failed to sync kubernetes service: %v", err)
+		}
+		return result, nil
+	case model.RegistryMethodServiceImport:
+		if err := w.syncServiceImport(rsp, force); err != nil {
+			return result, fmt.Errorf("failed to sync service import: %v", err)
+		}
+		return result, nil
+	}
 
 	newServiceEntry, newAnnotations := model.ConvertServiceEntry(rsp, polarisInfo)
 	if newServiceEntry == nil {
-		klog.Errorf("convertServiceEntry failed?")
-		return
+		return result, fmt.Errorf("convertServiceEntry failed")
 	}
 
 	oldServiceEntry, err := w.ic.NetworkingV1alpha3().ServiceEntries(w.configRootNS).Get(context.TODO(),
 		model.CovertServiceName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService), v1.GetOptions{})
 	if err != nil {
-		klog.Infof("[syncPolarisServices2Istio] get old service entries failed, error: %v", err)
-		return
+		// the ServiceEntry of the polaris service declared by a binding is created by polaris2istio
+		if source := w.sourceOf(polarisInfo); source.binding && apierrors.IsNotFound(err) {
+			w.converted(rsp, newServiceEntry)
+			if err := w.validateServiceEntry(polarisInfo, newServiceEntry); err != nil {
+				return result, err
+			}
+			return result, w.createBoundServiceEntry(source.name, rsp, polarisInfo, newServiceEntry, newAnnotations)
+		}
+		return result, fmt.Errorf("get old service entries failed, error: %v", err)
 	}
 
 	newServiceEntry.Addresses = append(newServiceEntry.Addresses, oldServiceEntry.Spec.GetAddresses()...)
+	w.converted(rsp, newServiceEntry)
 
-	if revision, exists := oldServiceEntry.GetAnnotations()["aeraki.net/revision"]; !exists ||
-		newAnnotations["aeraki.net/revision"] != revision {
+	oldRevision, exists := oldServiceEntry.GetAnnotations()["aeraki.net/revision"]
+	// the ServiceEntry is rewritten when the WorkloadEntry mode is switched, even if the revision is unchanged
+	modeSwitched := (oldServiceEntry.Spec.GetWorkloadSelector() != nil) != (newServiceEntry.WorkloadSelector != nil)
+	if !exists || newAnnotations["aeraki.net/revision"] != oldRevision || modeSwitched ||
+		(force && !serviceEntryEqual(oldServiceEntry, newServiceEntry, newAnnotations)) {
+		// the ServiceEntry rejected by istio would be discarded by istiod, the old one is kept instead
+		if err := w.validateServiceEntry(polarisInfo, newServiceEntry); err != nil {
+			return result, err
+		}
+		// sync the WorkloadEntries first, the revision is left unchanged on failure so that it will be retried
+		if polarisInfo.IsWorkloadEntryMode() {
+			if err := w.syncWorkloadEntries(rsp, serviceEntryOwner(oldServiceEntry)); err != nil {
+				return result, fmt.Errorf("failed to sync WorkloadEntries: %w", err)
+			}
+		}
+		serviceEntry := w.toServiceEntryCRD(model.CovertServiceName(polarisInfo.PolarisNamespace,
+			polarisInfo.PolarisService), newServiceEntry, oldServiceEntry, newAnnotations)
+		if w.dryRun != nil {
+			w.dryRun.Update("serviceentries", oldServiceEntry, serviceEntry)
+			return result, nil
+		}
 		klog.Infof("[syncPolarisServices2Istio] update serviceentry: %v", newServiceEntry)
 		_, err = w.ic.NetworkingV1alpha3().ServiceEntries(oldServiceEntry.Namespace).Update(context.TODO(),
-			w.toServiceEntryCRD(model.CovertServiceName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService),
-				newServiceEntry, oldServiceEntry, newAnnotations),
-			v1.UpdateOptions{FieldManager: aerakiFieldManager})
+			serviceEntry, v1.UpdateOptions{FieldManager: model.AerakiFieldManager})
 		if err != nil {
-			klog.Errorf("failed to update ServiceEntry: %s", err.Error())
+			return result, fmt.Errorf("failed to update ServiceEntry: %v",
+				metrics.KubernetesWriteError("serviceentries", err))
+		}
+		// the WorkloadEntries are deleted once the endpoints are inlined, so that the traffic is not interrupted
+		if modeSwitched && !polarisInfo.IsWorkloadEntryMode() {
+			if err := w.cleanWorkloadEntries(polarisInfo); err != nil {
+				return result, fmt.Errorf("failed to clean WorkloadEntries: %v", err)
+			}
 		}
 	} else {
 		log.Infof("[syncPolarisServices2Istio] serviceentry unchanged: %v", oldServiceEntry.GetName())
 	}
+	return result, nil
+}
+
+// validateServiceEntry checks the ServiceEntry converted from the polaris service before it is written
+func (w *ProviderWatcher) validateServiceEntry(polarisInfo *model.PolarisInfo, spec *istio.ServiceEntry) error {
+	name := model.CovertServiceName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	return metrics.ConversionError("serviceentries", model.ValidateServiceEntry(name, w.configRootNS, spec))
+}
+
+// serviceEntryEqual returns whether the service entry has the spec and the annotations
+func serviceEntryEqual(old *v1alpha3.ServiceEntry, spec *istio.ServiceEntry, annotations map[string]string) bool {
+	for k, v := range annotations {
+		if old.GetAnnotations()[k] != v {
+			return false
+		}
+	}
+	return proto.Equal(&old.Spec, spec)
 }
 
 func (w *ProviderWatcher) toServiceEntryCRD(name string, new *istio.ServiceEntry, old *v1alpha3.ServiceEntry,
@@ -137,17 +521,22 @@ func (w *ProviderWatcher) toServiceEntryCRD(name string, new *istio.ServiceEntry
 			Name:      name,
 			Namespace: w.configRootNS,
 			Labels: map[string]string{
-				"manager":  aerakiFieldManager,
-				"registry": "polaris",
+				model.ManagerLabel:  model.AerakiFieldManager,
+				model.RegistryLabel: model.PolarisRegistry,
 			},
 			Annotations: annotations,
 		},
-		Spec: *new,
 	}
+	new.DeepCopyInto(&serviceEntry.Spec)
 
 	if old != nil {
 		serviceEntry.ResourceVersion = old.ResourceVersion
 		serviceEntry.Labels = old.Labels
+		serviceEntry.OwnerReferences = old.OwnerReferences
+		// the sync status is written separately after the sync
+		if status, exists := old.GetAnnotations()[model.SyncStatusAnnotation]; exists {
+			serviceEntry.Annotations[model.SyncStatusAnnotation] = status
+		}
 	}
 
 	return serviceEntry
@@ -15,77 +15,246 @@
 package polaris
 
 import (
+	"sync"
 	"time"
 
+	bindingclient "github.com/aeraki-mesh/polaris2istio/pkg/client/clientset/versioned"
+	"github.com/aeraki-mesh/polaris2istio/pkg/debug"
+	"github.com/aeraki-mesh/polaris2istio/pkg/dryrun"
+	"github.com/aeraki-mesh/polaris2istio/pkg/events"
+	"github.com/aeraki-mesh/polaris2istio/pkg/freshness"
+	"github.com/aeraki-mesh/polaris2istio/pkg/health"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
 	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
 	istioclient "istio.io/client-go/pkg/clientset/versioned"
 	"istio.io/pkg/log"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	mcsclient "sigs.k8s.io/mcs-api/pkg/client/clientset/versioned"
+)
+
+const (
+	// scanInterval is how often the matched ServiceEntries are scanned
+	scanInterval = 10 * time.Second
+	// watcherLoop is the name of the scanning loop in the liveness
+	watcherLoop = "watcher"
 )
 
 // ServiceWatcher watches for newly created polaris services and creates a providerWatcher for each service
 type ServiceWatcher struct {
-	polarisclient  *polaris.PolarisClient
+	polarisclient  *polaris.FederatedClient
 	ic             *istioclient.Clientset
-	polarisAddress string
+	kc             kubernetes.Interface
+	mc             mcsclient.Interface
+	bc             bindingclient.Interface
 	registryMethod uint
 	configRootNS   string
+	targetNS       string
+	isLeader       func() bool
+	owns           func(namespace, service string) bool
+	freshness      *freshness.Tracker
+	syncState      *debug.Store
+	events         *events.Recorder
+	dryRun         *dryrun.Recorder
+	// the names of the source ServiceEntries keyed by the polaris namespace/service
+	sources *sync.Map
+	// the polaris services whose first sync is done, keyed by the polaris namespace/service
+	synced *sync.Map
+	// the PolarisServiceBindings whose polaris services are watched, keyed by their uids
+	subscribed *sync.Map
+}
+
+// Options are the options of the service watcher
+type Options struct {
+	// Clusters are the polaris clusters in descending priority
+	Clusters []polaris.ClusterConfig
+	// ConflictPolicy decides how the instances of a polaris service in several clusters are merged
+	ConflictPolicy polaris.ConflictPolicy
+	// SDK are the settings passed through to the polaris-go sdk
+	SDK polaris.SDKOptions
+	// RegistryMethod decides which configs the polaris services are synced to
+	RegistryMethod uint
+	// ConfigRootNS is the namespace of the matched ServiceEntries
+	ConfigRootNS string
+	// TargetNS is the namespace of the generated kubernetes services and service imports when the
+	// RegistryMethod is RegistryMethodKubernetesService or RegistryMethodServiceImport, default to ConfigRootNS
+	TargetNS string
+	// IsLeader returns whether this replica writes the configs, the polaris services are still watched
+	// by the standbys to keep their caches warm. It is always the leader if it is nil.
+	IsLeader func() bool
+	// Owns returns whether this replica writes the configs of the polaris service when the services are sharded
+	// across the replicas. It owns all the services if it is nil.
+	Owns func(namespace, service string) bool
+	// StaleThreshold is how long a service may stay out of sync before it is reported stale
+	StaleThreshold time.Duration
+	// Debug keeps the sync state of the polaris services for debugging
+	Debug bool
+	// Events records the sync outcomes as the events of the source ServiceEntries
+	Events events.Options
+	// DryRun logs the diffs of the writes instead of making them, the sync status and the events are not
+	// written either
+	DryRun bool
 }
 
 // NewServiceWatcher creates a new service watcher
-func NewServiceWatcher(polarisAddress string, registryMethod uint, configRootNS string) (*ServiceWatcher, error) {
-	polarisclient, err := polaris.NewPolarisClient(polarisAddress)
+func NewServiceWatcher(opts Options) (*ServiceWatcher, error) {
+	polarisclient, err := polaris.NewFederatedClient(opts.Clusters, opts.ConflictPolicy, opts.SDK)
 	if err != nil {
 		log.Errorf("failed to new polaris client consumer client: %v", err)
 		return nil, err
 	}
 
-	ic, err := getIstioClient()
+	ic, kc, mc, bc, err := getClients()
 	if err != nil {
-		log.Errorf("failed to create istio client: %v", err)
+		log.Errorf("failed to create kubernetes clients: %v", err)
 		return nil, err
 	}
 
+	if opts.TargetNS == "" {
+		opts.TargetNS = opts.ConfigRootNS
+	}
+
+	var syncState *debug.Store
+	if opts.Debug {
+		syncState = debug.NewStore()
+	}
+	var dryRun *dryrun.Recorder
+	if opts.DryRun {
+		dryRun = dryrun.NewRecorder()
+		opts.Events.Enabled = false
+	}
+
 	return &ServiceWatcher{
 		ic:             ic,
+		kc:             kc,
+		mc:             mc,
+		bc:             bc,
 		polarisclient:  polarisclient,
-		polarisAddress: polarisAddress,
-		registryMethod: registryMethod,
-		configRootNS:   configRootNS,
+		registryMethod: opts.RegistryMethod,
+		configRootNS:   opts.ConfigRootNS,
+		targetNS:       opts.TargetNS,
+		isLeader:       opts.IsLeader,
+		owns:           opts.Owns,
+		freshness:      freshness.NewTracker(opts.StaleThreshold),
+		syncState:      syncState,
+		events:         events.NewRecorder(kc, opts.Events),
+		dryRun:         dryRun,
+		sources:        new(sync.Map),
+		synced:         new(sync.Map),
+		subscribed:     new(sync.Map),
 	}, nil
 }
 
-func getIstioClient() (*istioclient.Clientset, error) {
+func getClients() (*istioclient.Clientset, kubernetes.Interface, mcsclient.Interface, bindingclient.Interface,
+	error) {
 	config, err := config.GetConfig()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	ic, err := istioclient.NewForConfig(config)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, nil, err
+	}
+
+	kc, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	mc, err := mcsclient.NewForConfig(config)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	bc, err := bindingclient.NewForConfig(config)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
-	return ic, nil
+	return ic, kc, mc, bc, nil
 }
 
 // Run a time ticker for watch
 func (w *ServiceWatcher) Run(stop <-chan struct{}) {
-	tickTimer := time.NewTicker(10 * time.Second)
+	tickTimer := time.NewTicker(scanInterval)
+	health.Default.Beat(watcherLoop, 3*scanInterval)
 	w.watchProviders(stop)
 	for {
 		select {
 		case <-tickTimer.C:
 			log.Info("received time ticker")
+			health.Default.Beat(watcherLoop, 3*scanInterval)
 			w.watchProviders(stop)
+			w.observeRevisions()
 		case <-stop:
 			log.Info("recieve stop chan,stoped")
+			if w.events != nil {
+				w.events.Shutdown()
+			}
 			return
 		}
 	}
 }
 
+// Resync converts all the matched polaris services again, and only writes the configs which are changed
+func (w *ServiceWatcher) Resync() {
+	log.Infof("start to resync the matched services")
+	w.newProviderWatcher().Resync()
+}
+
+func (w *ServiceWatcher) newProviderWatcher() *ProviderWatcher {
+	providerWatcher := NewProviderWatcher(w.ic, w.kc, w.mc, w.polarisclient, w.configRootNS,
+		w.registryMethod, w.targetNS)
+	providerWatcher.bc = w.bc
+	providerWatcher.isLeader = w.isLeader
+	providerWatcher.owns = w.owns
+	providerWatcher.freshness = w.freshness
+	providerWatcher.syncState = w.syncState
+	providerWatcher.events = w.events
+	providerWatcher.dryRun = w.dryRun
+	providerWatcher.sources = w.sources
+	providerWatcher.synced = w.synced
+	providerWatcher.subscribed = w.subscribed
+	return providerWatcher
+}
+
 func (w *ServiceWatcher) watchProviders(stop <-chan struct{}) {
-	providerWatcher := NewProviderWatcher(w.ic, w.polarisclient, w.configRootNS)
+	providerWatcher := w.newProviderWatcher()
 	log.Infof("start to scan the matched services for watch on polaris")
 	go providerWatcher.Run(stop)
 }
+
+// Freshness returns the freshness tracker of the services written by this replica
+func (w *ServiceWatcher) Freshness() *freshness.Tracker {
+	return w.freshness
+}
+
+// SyncState returns the sync state of the polaris services, it is nil unless Debug is enabled
+func (w *ServiceWatcher) SyncState() *debug.Store {
+	return w.syncState
+}
+
+// DryRun returns the writes which would be made, it is nil unless DryRun is enabled
+func (w *ServiceWatcher) DryRun() *dryrun.Recorder {
+	return w.dryRun
+}
+
+// LookupService returns the error of getting the instances of the polaris service, it wraps
+// polaris.ErrServiceNotFound if the service does not exist in any of the polaris clusters
+func (w *ServiceWatcher) LookupService(namespace, service string) error {
+	_, err := w.polarisclient.GetPolarisAllInstances(namespace, service)
+	return err
+}
+
+// observeRevisions observes the revisions of the tracked services from the cache of the sdk,
+// so that the services are reported stale even if their events are missed
+func (w *ServiceWatcher) observeRevisions() {
+	for _, key := range w.freshness.Services() {
+		rsp, err := w.polarisclient.GetPolarisAllInstances(key.Namespace, key.Service)
+		if err != nil {
+			log.Warnf("failed to observe the revision of %v/%v: %v", key.Namespace, key.Service, err)
+			continue
+		}
+		w.freshness.Observe(key.Namespace, key.Service, model.FilterInstances(rsp).GetRevision())
+	}
+}
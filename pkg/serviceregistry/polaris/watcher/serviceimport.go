@@ -0,0 +1,91 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polaris
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+	"k8s.io/apimachinery/pkg/api/equality"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	mcs "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+)
+
+// syncServiceImport projects the polaris service into a multi-cluster ServiceImport and its EndpointSlices,
+// the unchanged revision is not skipped if force is true
+func (w *ProviderWatcher) syncServiceImport(rsp *polarismodel.InstancesResponse, force bool) error {
+	newImport, newSlices := model.ConvertServiceImport(rsp, w.targetNS)
+	w.converted(rsp, &convertedObjects{Object: newImport.DeepCopy(), EndpointSlices: newSlices})
+	client := w.mc.MulticlusterV1alpha1().ServiceImports(w.targetNS)
+
+	return w.upsertProjection(rsp, force, &projection{
+		resource:         "serviceimports",
+		serviceNameLabel: mcs.LabelServiceName,
+		apiVersion:       mcs.SchemeGroupVersion.String(),
+		kind:             "ServiceImport",
+		object:           newImport,
+		slices:           newSlices,
+		get: func() (v1.Object, error) {
+			oldImport, err := client.Get(context.TODO(), newImport.Name, v1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return oldImport, nil
+		},
+		create: func() (v1.Object, error) {
+			return client.Create(context.TODO(), newImport, v1.CreateOptions{FieldManager: model.AerakiFieldManager})
+		},
+		update: func() error {
+			_, err := client.Update(context.TODO(), newImport, v1.UpdateOptions{FieldManager: model.AerakiFieldManager})
+			return err
+		},
+		specEqual: func(old v1.Object) bool {
+			return equality.Semantic.DeepEqual(old.(*mcs.ServiceImport).Spec.Ports, newImport.Spec.Ports)
+		},
+		merge: func(old v1.Object) {
+			oldImport := old.(*mcs.ServiceImport)
+			// the cluster set IPs are allocated by the multi-cluster services controller
+			newImport.Spec.IPs = oldImport.Spec.IPs
+			newImport.Status = oldImport.Status
+		},
+	})
+}
+
+// cleanServiceImports deletes the ServiceImports projected from the polaris services which are not declared any more
+func (w *ProviderWatcher) cleanServiceImports(declared map[string]bool) error {
+	client := w.mc.MulticlusterV1alpha1().ServiceImports(w.targetNS)
+	list, err := client.List(context.TODO(), v1.ListOptions{LabelSelector: projectionSelector()})
+	if err != nil {
+		return fmt.Errorf("list service imports failed: %v", err)
+	}
+	var errs []error
+	for i := range list.Items {
+		serviceImport := &list.Items[i]
+		if !w.isOrphanProjection(serviceImport, declared) {
+			continue
+		}
+		if err := w.deleteProjection("serviceimports", mcs.LabelServiceName, serviceImport, func() error {
+			return client.Delete(context.TODO(), serviceImport.Name, v1.DeleteOptions{})
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("clean service imports failed: %v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polaris
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+// statusRefreshInterval is how long an unchanged sync status is kept before its last sync time is refreshed,
+// so that the source ServiceEntries are not written on every sync
+const statusRefreshInterval = 5 * time.Minute
+
+// sourceRef is the source which declares the polaris service, a PolarisServiceBinding or a ServiceEntry
+type sourceRef struct {
+	name    string
+	binding bool
+}
+
+// trackSource remembers the name of the source of the polaris service
+func (w *ProviderWatcher) trackSource(polarisInfo *model.PolarisInfo, name string) {
+	if w.sources != nil {
+		w.sources.Store(polarisInfo.PolarisNamespace+"/"+polarisInfo.PolarisService,
+			sourceRef{name: name, binding: polarisInfo.Binding != ""})
+	}
+}
+
+// sourceOf returns the source of the polaris service, it is the generated ServiceEntry if the source is not
+// tracked yet
+func (w *ProviderWatcher) sourceOf(polarisInfo *model.PolarisInfo) sourceRef {
+	if w.sources != nil {
+		if source, exists := w.sources.Load(polarisInfo.PolarisNamespace + "/" + polarisInfo.PolarisService); exists {
+			return source.(sourceRef)
+		}
+	}
+	return sourceRef{name: model.CovertServiceName(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)}
+}
+
+// writeSyncStatus writes the sync status of the polaris service to the status of its binding, or to the annotation
+// of its source ServiceEntry, the last successful revision is kept when the sync fails
+func (w *ProviderWatcher) writeSyncStatus(polarisInfo *model.PolarisInfo, result syncResult, syncErr error) {
+	// nothing is synced in the dry run
+	if w.dryRun != nil {
+		return
+	}
+	source := w.sourceOf(polarisInfo)
+	if source.binding {
+		w.writeBindingStatus(source.name, result, syncErr)
+		return
+	}
+
+	name := source.name
+	client := w.ic.NetworkingV1alpha3().ServiceEntries(w.configRootNS)
+	se, err := client.Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		klog.Warningf("[writeSyncStatus] failed to get the source ServiceEntry %v: %v", name, err)
+		return
+	}
+	old, err := model.GetSyncStatus(se.GetAnnotations())
+	if err != nil {
+		klog.Warningf("[writeSyncStatus] %v, it is overwritten", err)
+	}
+	status, changed := nextSyncStatus(old, se.Generation, result, syncErr)
+	if !changed {
+		return
+	}
+
+	value, err := status.Annotation()
+	if err != nil {
+		klog.Errorf("[writeSyncStatus] failed to marshal the sync status of %v: %v", name, err)
+		return
+	}
+	// the resource version makes the patch fail if the generation is changed since it is got
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": se.ResourceVersion,
+			"annotations":     map[string]string{model.SyncStatusAnnotation: value},
+		},
+	})
+	if err != nil {
+		klog.Errorf("[writeSyncStatus] failed to marshal the patch of %v: %v", name, err)
+		return
+	}
+	if _, err := client.Patch(context.TODO(), name, types.MergePatchType, patch,
+		v1.PatchOptions{FieldManager: model.AerakiFieldManager}); err != nil {
+		klog.Warningf("[writeSyncStatus] failed to write the sync status of %v: %v", name,
+			metrics.KubernetesWriteError("serviceentries", err))
+	}
+}
+
+// nextSyncStatus returns the sync status after the sync, and whether it should be written
+func nextSyncStatus(old *model.SyncStatus, generation int64, result syncResult,
+	syncErr error) (*model.SyncStatus, bool) {
+	now := v1.Now()
+	status := &model.SyncStatus{
+		LastSyncTime:       now,
+		Endpoints:          result.endpoints,
+		ObservedGeneration: generation,
+	}
+	if old != nil {
+		status.LastSuccessTime = old.LastSuccessTime
+		status.Revision = old.Revision
+		// the instances are not got if the revision is empty
+		if result.revision == "" {
+			status.Endpoints = old.Endpoints
+		}
+	}
+	if syncErr != nil {
+		status.LastError = syncErr.Error()
+	} else {
+		status.LastSuccessTime = &now
+		status.Revision = result.revision
+	}
+	if old != nil && syncStatusEqual(old, status) && now.Sub(old.LastSyncTime.Time) < statusRefreshInterval {
+		return status, false
+	}
+	return status, true
+}
+
+// syncStatusEqual returns whether the sync statuses are the same except the times
+func syncStatusEqual(a, b *model.SyncStatus) bool {
+	x, y := *a, *b
+	x.LastSyncTime, y.LastSyncTime = v1.Time{}, v1.Time{}
+	x.LastSuccessTime, y.LastSuccessTime = nil, nil
+	return reflect.DeepEqual(x, y)
+}
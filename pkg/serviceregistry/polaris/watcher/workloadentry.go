@@ -0,0 +1,152 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polaris
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/metrics"
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+	"google.golang.org/protobuf/proto"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// syncWorkloadEntries creates, updates and deletes the WorkloadEntries of the polaris service,
+// so that only the WorkloadEntries of the changed instances are rewritten. The WorkloadEntries are owned by the
+// owner, so that they are garbage collected with it.
+func (w *ProviderWatcher) syncWorkloadEntries(rsp *polarismodel.InstancesResponse, owner v1.OwnerReference) error {
+	// nothing is written if any of the WorkloadEntries is rejected by istio, the old ones are kept
+	entries := model.ConvertWorkloadEntries(rsp, w.configRootNS)
+	if err := metrics.ConversionError("workloadentries", model.ValidateWorkloadEntries(entries)); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		entry.OwnerReferences = []v1.OwnerReference{owner}
+	}
+
+	olds, err := w.listWorkloadEntries(rsp.GetNamespace(), rsp.GetService())
+	if err != nil {
+		return err
+	}
+	client := w.ic.NetworkingV1alpha3().WorkloadEntries(w.configRootNS)
+
+	var errs []error
+	for _, entry := range entries {
+		old, exists := olds[entry.Name]
+		delete(olds, entry.Name)
+		if !exists {
+			if w.dryRun != nil {
+				w.dryRun.Create("workloadentries", entry)
+				continue
+			}
+			klog.Infof("[syncWorkloadEntries] create workloadentry: %v", entry.Name)
+			if _, err := client.Create(context.TODO(), entry,
+				v1.CreateOptions{FieldManager: model.AerakiFieldManager}); err != nil {
+				errs = append(errs, metrics.KubernetesWriteError("workloadentries", err))
+			}
+			continue
+		}
+		if workloadEntryEqual(old, entry) {
+			continue
+		}
+		entry.ResourceVersion = old.ResourceVersion
+		if w.dryRun != nil {
+			w.dryRun.Update("workloadentries", old, entry)
+			continue
+		}
+		klog.Infof("[syncWorkloadEntries] update workloadentry: %v", entry.Name)
+		if _, err := client.Update(context.TODO(), entry,
+			v1.UpdateOptions{FieldManager: model.AerakiFieldManager}); err != nil {
+			errs = append(errs, metrics.KubernetesWriteError("workloadentries", err))
+		}
+	}
+
+	errs = append(errs, w.deleteWorkloadEntries(olds)...)
+	if len(errs) > 0 {
+		return fmt.Errorf("sync workload entries failed: %v", errs)
+	}
+	return nil
+}
+
+// cleanWorkloadEntries deletes the WorkloadEntries of the polaris service which is not in the WorkloadEntry mode
+// any more, they would still receive the traffic through their labels
+func (w *ProviderWatcher) cleanWorkloadEntries(polarisInfo *model.PolarisInfo) error {
+	olds, err := w.listWorkloadEntries(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	if err != nil {
+		return err
+	}
+	if errs := w.deleteWorkloadEntries(olds); len(errs) > 0 {
+		return fmt.Errorf("delete workload entries failed: %v", errs)
+	}
+	return nil
+}
+
+// listWorkloadEntries returns the WorkloadEntries generated for the polaris service keyed by their names
+func (w *ProviderWatcher) listWorkloadEntries(namespace, service string) (map[string]*v1alpha3.WorkloadEntry,
+	error) {
+	list, err := w.ic.NetworkingV1alpha3().WorkloadEntries(w.configRootNS).List(context.TODO(), v1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s, %s=%s", model.ManagerLabel, model.AerakiFieldManager,
+			model.PolarisServiceLabel, model.WorkloadSelectorLabelValue(namespace, service)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list workload entries failed: %v", err)
+	}
+	entries := make(map[string]*v1alpha3.WorkloadEntry, len(list.Items))
+	for i := range list.Items {
+		entries[list.Items[i].Name] = &list.Items[i]
+	}
+	return entries, nil
+}
+
+func (w *ProviderWatcher) deleteWorkloadEntries(entries map[string]*v1alpha3.WorkloadEntry) []error {
+	var errs []error
+	for name, old := range entries {
+		if w.dryRun != nil {
+			w.dryRun.Delete("workloadentries", old)
+			continue
+		}
+		klog.Infof("[syncWorkloadEntries] delete workloadentry: %v", name)
+		err := w.ic.NetworkingV1alpha3().WorkloadEntries(w.configRootNS).Delete(context.TODO(), name,
+			v1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, metrics.KubernetesWriteError("workloadentries", err))
+		}
+	}
+	return errs
+}
+
+// workloadEntryEqual compares the owners too, so that the WorkloadEntries written without an owner are adopted
+func workloadEntryEqual(old *v1alpha3.WorkloadEntry, new *v1alpha3.WorkloadEntry) bool {
+	return proto.Equal(&old.Spec, &new.Spec) &&
+		reflect.DeepEqual(old.Labels, new.Labels) &&
+		reflect.DeepEqual(old.Annotations, new.Annotations) &&
+		reflect.DeepEqual(old.OwnerReferences, new.OwnerReferences)
+}
+
+// serviceEntryOwner returns the reference to the ServiceEntry as the owner of the objects generated for it
+func serviceEntryOwner(se *v1alpha3.ServiceEntry) v1.OwnerReference {
+	return v1.OwnerReference{
+		APIVersion: v1alpha3.SchemeGroupVersion.String(),
+		Kind:       "ServiceEntry",
+		Name:       se.Name,
+		UID:        se.UID,
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/pkg/log"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// placeholderResolution is the resolution of the defaulted ServiceEntries, it is the resolution written by the sync
+var placeholderResolution = istio.ServiceEntry_STATIC.String()
+
+// patchOperation is an operation of the JSON patch
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Defaulter fills in the polaris-managed ServiceEntries, so that only the polaris namespace and service are required:
+// the hosts from the hostname template, the manager and registry labels, the aeraki.net/external annotation and
+// the resolution. The fields which are set are left alone.
+type Defaulter struct{}
+
+// ServeHTTP serves the mutating admission reviews
+func (d *Defaulter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveAdmission(w, r, d.admit)
+}
+
+func (d *Defaulter) admit(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if !isServiceEntry(req) || req.Operation == admissionv1.Delete {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	se := &v1alpha3.ServiceEntry{}
+	if err := json.Unmarshal(req.Object.Raw, se); err != nil {
+		return denied(http.StatusBadRequest, fmt.Sprintf("failed to decode the ServiceEntry: %v", err))
+	}
+	// the presence of the enums is lost in the decoded spec
+	raw := struct {
+		Spec map[string]interface{} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(req.Object.Raw, &raw); err != nil {
+		return denied(http.StatusBadRequest, fmt.Sprintf("failed to decode the ServiceEntry: %v", err))
+	}
+
+	patch := defaultServiceEntry(se, raw.Spec)
+	if len(patch) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return denied(http.StatusInternalServerError, fmt.Sprintf("failed to marshal the patch: %v", err))
+	}
+	log.Debugf("[webhook] defaulted ServiceEntry %v/%v: %s", req.Namespace, req.Name, data)
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{Allowed: true, Patch: data, PatchType: &patchType}
+}
+
+// defaultServiceEntry returns the patch which fills in the ServiceEntry, it is empty if the ServiceEntry does not
+// refer to a polaris service
+func defaultServiceEntry(se *v1alpha3.ServiceEntry, spec map[string]interface{}) []patchOperation {
+	polarisInfo, err := model.GetPolarisInfoFromSEAnnotations(se.GetAnnotations())
+	if err != nil || polarisInfo.PolarisNamespace == "" || polarisInfo.PolarisService == "" {
+		return nil
+	}
+
+	var patch []patchOperation
+	labels := map[string]string{model.ManagerLabel: model.AerakiFieldManager, model.RegistryLabel: model.PolarisRegistry}
+	if se.Labels == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/labels", Value: labels})
+	} else {
+		for _, key := range []string{model.ManagerLabel, model.RegistryLabel} {
+			if _, exists := se.Labels[key]; !exists {
+				patch = append(patch, patchOperation{Op: "add", Path: "/metadata/labels/" + escape(key),
+					Value: labels[key]})
+			}
+		}
+	}
+	if _, exists := se.Annotations["aeraki.net/external"]; !exists {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations/" +
+			escape("aeraki.net/external"), Value: polarisInfo.External})
+	}
+
+	host := model.CovertServiceHostname(polarisInfo.PolarisNamespace, polarisInfo.PolarisService)
+	if spec == nil {
+		return append(patch, patchOperation{Op: "add", Path: "/spec", Value: map[string]interface{}{
+			"hosts":      []string{host},
+			"resolution": placeholderResolution,
+		}})
+	}
+	if len(se.Spec.Hosts) == 0 {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/hosts", Value: []string{host}})
+	}
+	if _, exists := spec["resolution"]; !exists {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/resolution", Value: placeholderResolution})
+	}
+	return patch
+}
+
+// escape escapes the key as a reference token of the JSON pointer
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
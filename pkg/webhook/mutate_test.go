@@ -0,0 +1,90 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/stretchr/testify/assert"
+	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+)
+
+// mutate sends the ServiceEntry to the defaulter, and returns the ServiceEntry patched by the response
+func mutate(t *testing.T, se *v1alpha3.ServiceEntry) *v1alpha3.ServiceEntry {
+	rsp := review(t, &Defaulter{}, se)
+	assert.True(t, rsp.Allowed)
+	if rsp.Patch == nil {
+		return se
+	}
+	patch, err := jsonpatch.DecodePatch(rsp.Patch)
+	assert.Nil(t, err)
+	raw, err := json.Marshal(se)
+	assert.Nil(t, err)
+	patched, err := patch.Apply(raw)
+	assert.Nil(t, err)
+	out := &v1alpha3.ServiceEntry{}
+	assert.Nil(t, json.Unmarshal(patched, out))
+	return out
+}
+
+func TestDefaulter(t *testing.T) {
+	assert := assert.New(t)
+	se := newTestServiceEntry(map[string]string{
+		"aeraki.net/polarisNamespace": "Production",
+		"aeraki.net/polarisService":   "reviews",
+	})
+	se.Labels = nil
+	out := mutate(t, se)
+	assert.Equal(map[string]string{"manager": "aeraki", "registry": "polaris"}, out.Labels)
+	assert.Equal("true", out.Annotations["aeraki.net/external"])
+	assert.Equal([]string{"production.polaris-reviews.polaris"}, out.Spec.Hosts)
+	assert.Equal(istio.ServiceEntry_STATIC, out.Spec.Resolution)
+
+	// the fields which are set are left alone
+	se = newTestServiceEntry(map[string]string{
+		"aeraki.net/polarisNamespace": "Production",
+		"aeraki.net/polarisService":   "reviews",
+		"aeraki.net/external":         "false",
+	})
+	se.Labels = map[string]string{"registry": "polaris", "team": "bookinfo"}
+	se.Spec.Hosts = []string{"reviews.bookinfo.polaris"}
+	out = mutate(t, se)
+	assert.Equal(map[string]string{"manager": "aeraki", "registry": "polaris", "team": "bookinfo"}, out.Labels)
+	assert.Equal("false", out.Annotations["aeraki.net/external"])
+	assert.Equal([]string{"reviews.bookinfo.polaris"}, out.Spec.Hosts)
+	assert.Equal(istio.ServiceEntry_STATIC, out.Spec.Resolution)
+
+	// the ServiceEntries which do not refer to a polaris service are left to the validation
+	se = newTestServiceEntry(map[string]string{"aeraki.net/polarisNamespace": "Production"})
+	se.Labels = nil
+	assert.Equal(se, mutate(t, se))
+}
+
+func TestDefaultServiceEntryResolution(t *testing.T) {
+	assert := assert.New(t)
+	se := newTestServiceEntry(map[string]string{
+		"aeraki.net/polarisNamespace": "Production",
+		"aeraki.net/polarisService":   "reviews",
+		"aeraki.net/external":         "true",
+	})
+	se.Spec.Hosts = []string{"reviews.bookinfo.polaris"}
+	// the explicit NONE resolution is kept
+	assert.Empty(defaultServiceEntry(se, map[string]interface{}{"resolution": "NONE"}))
+	assert.Equal([]patchOperation{{Op: "add", Path: "/spec/resolution", Value: "STATIC"}},
+		defaultServiceEntry(se, map[string]interface{}{"hosts": []interface{}{"reviews.bookinfo.polaris"}}))
+}
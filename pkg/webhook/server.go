@@ -0,0 +1,153 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"istio.io/pkg/log"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	shutdownTimeout = 5 * time.Second
+	// maxRequestSize is the limit of the admission reviews, the objects are limited to 1.5MiB by the api server
+	maxRequestSize = 3 << 20
+	// ValidatePath is the path of the validating webhook
+	ValidatePath = "/validate"
+	// MutatePath is the path of the mutating webhook
+	MutatePath = "/mutate"
+)
+
+// serviceEntryResource is the resource the webhooks admit, the ServiceEntries of all the versions have the same schema
+var serviceEntryResource = schema.GroupResource{Group: "networking.istio.io", Resource: "serviceentries"}
+
+// Options are the options of the webhook server
+type Options struct {
+	// Address is the listen address of the webhooks
+	Address  string
+	CertFile string
+	KeyFile  string
+	// LookupService returns an error if the polaris service can not be got, the ServiceEntries referring to
+	// the missing polaris services are admitted with a warning. The services are not looked up if it is nil.
+	LookupService func(namespace, service string) error
+}
+
+// Server serves the admission webhooks of the polaris-managed ServiceEntries over https
+type Server struct {
+	mux      *http.ServeMux
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer listens on the address and serves the validating webhook on /validate and the mutating webhook
+// on /mutate
+func NewServer(opts Options) (*Server, error) {
+	cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the certificate of the webhooks: %v", err)
+	}
+	listener, err := net.Listen("tcp", opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %v: %v", opts.Address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(ValidatePath, &Validator{LookupService: opts.LookupService})
+	mux.Handle(MutatePath, &Defaulter{})
+	return &Server{
+		mux: mux,
+		listener: tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}),
+		server: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+	}, nil
+}
+
+// Handle adds the handler of the path
+func (s *Server) Handle(path string, handler http.Handler) {
+	s.mux.Handle(path, handler)
+}
+
+// Address returns the address the server listens on
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Run serves until the stop channel is closed
+func (s *Server) Run(stop <-chan struct{}) {
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := s.server.Shutdown(ctx); err != nil {
+			log.Warnf("[webhook] failed to shutdown the server: %v", err)
+		}
+	}()
+	log.Infof("[webhook] serving on %v", s.Address())
+	if err := s.server.Serve(s.listener); err != nil && err != http.ErrServerClosed {
+		log.Errorf("[webhook] failed to serve on %v: %v", s.Address(), err)
+	}
+}
+
+// admitFunc admits the request, the response is completed with the uid of the request
+type admitFunc func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// serveAdmission decodes the admission review, and writes the response of the admit function
+func serveAdmission(w http.ResponseWriter, r *http.Request, admit admitFunc) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read the request: %v", err), http.StatusBadRequest)
+		return
+	}
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("invalid admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rsp := admit(review.Request)
+	rsp.UID = review.Request.UID
+	review.Response = rsp
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Errorf("[webhook] failed to write the admission response: %v", err)
+	}
+}
+
+func isServiceEntry(req *admissionv1.AdmissionRequest) bool {
+	return req.Resource.Group == serviceEntryResource.Group && req.Resource.Resource == serviceEntryResource.Resource
+}
+
+func denied(code int32, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result:  &v1.Status{Status: v1.StatusFailure, Code: code, Message: message},
+	}
+}
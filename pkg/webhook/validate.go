@@ -0,0 +1,72 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/model"
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/pkg/log"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// Validator rejects the malformed polaris-managed ServiceEntries, the other ServiceEntries are always allowed
+type Validator struct {
+	// LookupService returns an error if the polaris service can not be got, it is not looked up if it is nil
+	LookupService func(namespace, service string) error
+}
+
+// ServeHTTP serves the validating admission reviews
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveAdmission(w, r, v.admit)
+}
+
+func (v *Validator) admit(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if !isServiceEntry(req) || req.Operation == admissionv1.Delete {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	se := &v1alpha3.ServiceEntry{}
+	if err := json.Unmarshal(req.Object.Raw, se); err != nil {
+		return denied(http.StatusBadRequest, fmt.Sprintf("failed to decode the ServiceEntry: %v", err))
+	}
+	if !model.IsPolarisServiceEntry(se) {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	if err := model.ValidatePolarisServiceEntry(se); err != nil {
+		log.Infof("[webhook] rejected ServiceEntry %v/%v: %v", req.Namespace, req.Name, err)
+		return denied(http.StatusUnprocessableEntity, fmt.Sprintf("invalid polaris ServiceEntry: %v", err))
+	}
+
+	rsp := &admissionv1.AdmissionResponse{Allowed: true}
+	if v.LookupService != nil {
+		polarisInfo, err := model.GetPolarisInfoFromSEAnnotations(se.GetAnnotations())
+		if err != nil {
+			return rsp
+		}
+		// the other errors are not the fault of the ServiceEntry, such as the polaris is unreachable
+		if err := v.LookupService(polarisInfo.PolarisNamespace, polarisInfo.PolarisService); err != nil &&
+			errors.Is(err, polaris.ErrServiceNotFound) {
+			rsp.Warnings = append(rsp.Warnings, fmt.Sprintf("polaris service %s/%s is not found in any of the "+
+				"polaris clusters, it is synced once it is registered", polarisInfo.PolarisNamespace,
+				polarisInfo.PolarisService))
+		}
+	}
+	return rsp
+}
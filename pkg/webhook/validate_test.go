@@ -0,0 +1,122 @@
+// Copyright Aeraki Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	polaris "github.com/aeraki-mesh/polaris2istio/pkg/serviceregistry/polaris/sdk"
+	"github.com/stretchr/testify/assert"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestServiceEntry(annotations map[string]string) *v1alpha3.ServiceEntry {
+	return &v1alpha3.ServiceEntry{
+		TypeMeta: v1.TypeMeta{APIVersion: "networking.istio.io/v1alpha3", Kind: "ServiceEntry"},
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "reviews",
+			Namespace:   "polaris",
+			Labels:      map[string]string{"manager": "aeraki", "registry": "polaris"},
+			Annotations: annotations,
+		},
+	}
+}
+
+// review sends the ServiceEntry to the handler, and returns the admission response
+func review(t *testing.T, handler http.Handler, se *v1alpha3.ServiceEntry) *admissionv1.AdmissionResponse {
+	raw, err := json.Marshal(se)
+	assert.Nil(t, err)
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: v1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("uid-1"),
+			Resource:  v1.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "serviceentries"},
+			Operation: admissionv1.Create,
+			Namespace: se.Namespace,
+			Name:      se.Name,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	})
+	assert.Nil(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", ValidatePath, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	rsp := &admissionv1.AdmissionReview{}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), rsp))
+	assert.Equal(t, types.UID("uid-1"), rsp.Response.UID)
+	return rsp.Response
+}
+
+func TestValidator(t *testing.T) {
+	assert := assert.New(t)
+	validator := &Validator{}
+
+	rsp := review(t, validator, newTestServiceEntry(map[string]string{
+		"aeraki.net/polarisNamespace": "Production",
+		"aeraki.net/polarisService":   "reviews",
+	}))
+	assert.True(rsp.Allowed)
+
+	rsp = review(t, validator, newTestServiceEntry(map[string]string{
+		"aeraki.net/polarisNamespace": "Production",
+		"aeraki.net/polarisServce":    "reviews",
+		"aeraki.net/external":         "False",
+	}))
+	assert.False(rsp.Allowed)
+	assert.Equal("invalid polaris ServiceEntry: annotation aeraki.net/polarisService is required; "+
+		"annotation aeraki.net/external should be \"true\" or \"false\", got \"False\"; "+
+		"unknown annotation aeraki.net/polarisServce, did you mean aeraki.net/polarisService?", rsp.Result.Message)
+
+	// the ServiceEntries which are not synced from the polaris are left alone
+	se := newTestServiceEntry(nil)
+	se.Labels = nil
+	assert.True(review(t, validator, se).Allowed)
+}
+
+func TestValidatorLookupService(t *testing.T) {
+	assert := assert.New(t)
+	validator := &Validator{LookupService: func(namespace, service string) error {
+		switch service {
+		case "reviews":
+			return nil
+		case "ratings":
+			return fmt.Errorf("%w in all the polaris clusters", polaris.ErrServiceNotFound)
+		default:
+			return fmt.Errorf("polaris is unreachable")
+		}
+	}}
+	for service, warnings := range map[string][]string{
+		"reviews": nil,
+		"ratings": {"polaris service Production/ratings is not found in any of the polaris clusters, " +
+			"it is synced once it is registered"},
+		"details": nil,
+	} {
+		rsp := review(t, validator, newTestServiceEntry(map[string]string{
+			"aeraki.net/polarisNamespace": "Production",
+			"aeraki.net/polarisService":   service,
+		}))
+		assert.True(rsp.Allowed)
+		assert.Equal(warnings, rsp.Warnings, service)
+	}
+}